@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// git aliases already work since everything tiger doesn't know goes to git,
+// except when one has the same name as a tiger command. tiger wins unless the
+// alias is listed in tiger.preferAlias (can be specified multiple times, *
+// means every alias):
+//
+//	git config --global --add tiger.preferAlias ci
+
+func init() {
+	register(&builtin{
+		name:  "alias",
+		usage: "alias [name]",
+		about: "list git aliases or show what one expands to",
+		complete: func(args []string) []string {
+			return completeWords(lastArg(args), aliasNames())
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			aliases := gitAliases()
+			names := aliasNames()
+			if len(args) > 1 {
+				names = args[1:]
+			}
+			for _, name := range names {
+				expansion, ok := aliases[name]
+				if !ok {
+					return fmt.Errorf("not a git alias: %s", name)
+				}
+				fmt.Fprintln(io.out, Cyan+name+Reset+" = "+expansion+aliasShadowed(name))
+			}
+			return nil
+		},
+	})
+}
+
+// alias.name -> expansion
+func gitAliases() map[string]string {
+	aliases := map[string]string{}
+	for key, values := range cachedConfig() {
+		if name := strings.TrimPrefix(key, "alias."); name != "" && name != key {
+			aliases[name] = values[len(values)-1]
+		}
+	}
+	return aliases
+}
+
+func aliasNames() []string {
+	names := []string{}
+	for name := range gitAliases() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func preferAlias(name string) bool {
+	prefer := strings.Fields(strings.Join(cachedConfig()[configKey("tiger.preferAlias")], " "))
+	return contains(prefer, "*") || contains(prefer, name)
+}
+
+// name is a tiger command, but the user would rather have their git alias
+func aliasWins(name string) bool {
+	if !preferAlias(name) {
+		return false
+	}
+	_, ok := gitAliases()[name]
+	return ok
+}
+
+func aliasShadowed(name string) string {
+	c, ok := lookup(name)
+	if !ok {
+		return ""
+	}
+	if preferAlias(name) {
+		return Grey + " (instead of tiger's " + c.Name() + ")" + Reset
+	}
+	return Grey + " (tiger's " + c.Name() + " is used instead, to change that: git config --add tiger.preferAlias " + name + ")" + Reset
+}
+
+// aliases which tiger commands are hiding, that nobody said anything about
+func aliasNotice() string {
+	names := []string{}
+	for _, name := range aliasNames() {
+		if _, ok := lookup(name); ok && !preferAlias(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return Yellow + "git aliases:" + Reset + " " + strings.Join(names, " ") + " hidden by tiger commands (see: alias " + names[0] + ")"
+}
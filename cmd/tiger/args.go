@@ -1,5 +1,14 @@
 package main
 
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
 func splitArgs(input string) (args []string) {
 	var (
 		in   = []rune(input)
@@ -43,3 +52,421 @@ func splitArgs(input string) (args []string) {
 
 	return
 }
+
+// a command line is pipelines separated by && || ;
+//
+//	add . && commit -m x; log --oneline | grep fix > fixes.txt
+type cmdList struct {
+	pipelines []*pipeline
+	ops       []string // ops[i] is between pipelines[i] and pipelines[i+1]
+}
+
+type pipeline struct {
+	cmds []*simpleCmd
+}
+
+type simpleCmd struct {
+	args   []string
+	redirs []redirect
+}
+
+type redirect struct {
+	op   string // > >> <
+	file string
+}
+
+type token struct {
+	text string
+	op   bool
+	pos  int // where an op is in the line, in runes
+}
+
+var operators = []string{"&&", "||", ">>", "|", ";", ">", "<"}
+
+// same quoting rules as splitArgs, but:
+//   - | & ; < > outside of quotes are operators
+//   - $VAR and ${VAR} are expanded, except in single quotes
+//   - so are $0 $1 ... $# $@ when running a macro, params[0] is its name
+//   - $? is the exit status of the last command
+//   - ~ and {a,b} {1..3} are expanded outside of quotes
+func lex(input string, params ...string) []token {
+	return lexLine(input, true, params)
+}
+
+// without expand, $VAR and friends are left as they are
+func lexLine(input string, expand bool, params []string) (tokens []token) {
+	var (
+		in      = []rune(input)
+		arg     = &word{}
+		started bool // so that '' is still an argument
+		quot    rune
+		esc     bool
+	)
+
+	end := func() {
+		if started {
+			for _, w := range expandBraces(arg) {
+				tokens = append(tokens, token{text: w.expandTilde()})
+			}
+		}
+		arg = &word{}
+		started = false
+	}
+
+	for i := 0; i < len(in); i++ {
+		r := in[i]
+		switch {
+		case r == '\\':
+			if esc || i == len(in)-1 {
+				arg.add(r, true)
+			}
+			esc = !esc
+			started = true
+		case r == '\'' || r == '"':
+			if (quot == 0 || quot == r) && !esc {
+				if quot == 0 {
+					quot = r
+				} else {
+					quot = 0
+				}
+			} else {
+				arg.add(r, true)
+				esc = false
+			}
+			started = true
+		case r == '$' && quot != '\'' && !esc:
+			name, n := varName(in[i+1:])
+			if n == 0 {
+				arg.add(r, quot != 0)
+				started = true
+				continue
+			}
+			if !expand {
+				for _, v := range in[i : i+n+1] {
+					arg.add(v, true)
+				}
+				i += n
+				started = true
+				continue
+			}
+			i += n
+			if name == "@" {
+				// one argument each, like "$@"
+				for j, p := range params {
+					if j == 0 {
+						continue // $0
+					}
+					if j > 1 {
+						end()
+					}
+					for _, v := range p {
+						arg.add(v, true)
+					}
+					started = true
+				}
+				continue
+			}
+			value := expandVar(name, params)
+			for _, v := range value {
+				arg.add(v, true)
+			}
+			// an unquoted $EMPTY isn't an argument, "$EMPTY" is
+			started = started || quot != 0 || value != ""
+		case r == ' ' || r == '\t':
+			if quot != 0 || esc {
+				arg.add(r, true)
+				esc = false
+			} else {
+				end()
+			}
+		case strings.ContainsRune("|&;<>", r) && quot == 0 && !esc:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(string(in[i:]), o) {
+					op = o
+					break
+				}
+			}
+			if op == "" { // a lonely &
+				arg.add(r, false)
+				started = true
+				continue
+			}
+			end()
+			tokens = append(tokens, token{text: op, op: true, pos: i})
+			i += len(op) - 1
+		default:
+			arg.add(r, quot != 0 || esc)
+			esc = false
+			started = true
+		}
+	}
+	end()
+
+	return
+}
+
+// a word while it's being lexed, lit[i] is true when arg[i] was quoted,
+// escaped or came out of a variable and shouldn't be expanded any further
+type word struct {
+	arg []rune
+	lit []bool
+}
+
+func (w *word) add(r rune, lit bool) {
+	w.arg = append(w.arg, r)
+	w.lit = append(w.lit, lit)
+}
+
+func (w *word) slice(i, j int) *word {
+	return &word{w.arg[i:j], w.lit[i:j]}
+}
+
+func joinWords(words ...*word) *word {
+	joined := &word{}
+	for _, w := range words {
+		joined.arg = append(joined.arg, w.arg...)
+		joined.lit = append(joined.lit, w.lit...)
+	}
+	return joined
+}
+
+// ~ and ~/something
+func (w *word) expandTilde() string {
+	if len(w.arg) > 0 && w.arg[0] == '~' && !w.lit[0] && (len(w.arg) == 1 || w.arg[1] == '/') {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + string(w.arg[1:])
+		}
+	}
+	return string(w.arg)
+}
+
+// NAME, {NAME} or one of 0-9 # @ ? at the start of in, n is how many runes that was
+func varName(in []rune) (name string, n int) {
+	isName := func(r rune, first bool) bool {
+		return r == '_' || unicode.IsLetter(r) || (!first && unicode.IsDigit(r))
+	}
+	if len(in) > 1 && in[0] == '{' {
+		for j := 1; j < len(in); j++ {
+			if in[j] == '}' {
+				return string(in[1:j]), j + 1
+			}
+		}
+		return "", 0
+	}
+	if len(in) > 0 && strings.ContainsRune("0123456789#@?", in[0]) {
+		return string(in[0]), 1
+	}
+	for n < len(in) && isName(in[n], n == 0) {
+		n++
+	}
+	return string(in[:n]), n
+}
+
+func expandVar(name string, params []string) string {
+	if name == "?" {
+		return strconv.Itoa(int(atomic.LoadInt32(&lastStatus)))
+	}
+	if name == "#" {
+		if len(params) == 0 {
+			return "0"
+		}
+		return strconv.Itoa(len(params) - 1)
+	}
+	if i, err := strconv.Atoi(name); err == nil {
+		if i < len(params) {
+			return params[i]
+		}
+		return ""
+	}
+	return os.Getenv(name)
+}
+
+// {a,b,c} {1..10} {a..e} {10..0..2}, like bash
+func expandBraces(w *word) []*word {
+	for open := 0; open < len(w.arg); open++ {
+		if w.arg[open] != '{' || w.lit[open] {
+			continue
+		}
+
+		depth, close, commas := 0, -1, []int{}
+	scan:
+		for j := open + 1; j < len(w.arg); j++ {
+			if w.lit[j] {
+				continue
+			}
+			switch w.arg[j] {
+			case '{':
+				depth++
+			case '}':
+				if depth == 0 {
+					close = j
+					break scan
+				}
+				depth--
+			case ',':
+				if depth == 0 {
+					commas = append(commas, j)
+				}
+			}
+		}
+		if close < 0 {
+			continue
+		}
+
+		alts := []*word{}
+		if len(commas) > 0 {
+			start := open + 1
+			for _, c := range append(commas, close) {
+				alts = append(alts, w.slice(start, c))
+				start = c + 1
+			}
+		} else if r, ok := braceRange(string(w.arg[open+1 : close])); ok {
+			for _, s := range r {
+				alts = append(alts, &word{[]rune(s), make([]bool, len([]rune(s)))})
+			}
+		} else {
+			continue // e.g. stash@{0}
+		}
+
+		words := []*word{}
+		prefix, suffix := w.slice(0, open), w.slice(close+1, len(w.arg))
+		for _, alt := range alts {
+			words = append(words, expandBraces(joinWords(prefix, alt, suffix))...)
+		}
+		return words
+	}
+	return []*word{w}
+}
+
+func braceRange(s string) ([]string, bool) {
+	parts := strings.Split(s, "..")
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, false
+	}
+	step := 1
+	if len(parts) == 3 {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || n == 0 {
+			return nil, false
+		}
+		if n < 0 {
+			n = -n
+		}
+		step = n
+	}
+
+	var (
+		from, to int
+		char     bool
+		err1     error
+		err2     error
+	)
+	from, err1 = strconv.Atoi(parts[0])
+	to, err2 = strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		a, b := []rune(parts[0]), []rune(parts[1])
+		if len(a) != 1 || len(b) != 1 {
+			return nil, false
+		}
+		from, to, char = int(a[0]), int(b[0]), true
+	}
+	if from > to {
+		step = -step
+	}
+
+	r := []string{}
+	for i := from; (step > 0 && i <= to) || (step < 0 && i >= to); i += step {
+		if char {
+			r = append(r, string(rune(i)))
+		} else {
+			r = append(r, strconv.Itoa(i))
+		}
+	}
+	return r, true
+}
+
+func parseLine(input string, params ...string) (*cmdList, error) {
+	return parseTokens(lex(input, params...))
+}
+
+// the pipelines in a line as they were typed, with the && || ; between
+// them. each one is expanded right before it runs, so $? and $VAR see
+// what happened before it
+func splitLine(input string) (pipelines, ops []string, err error) {
+	tokens := lexLine(input, false, nil)
+	list, err := parseTokens(tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+	in := []rune(input)
+	start, words := 0, false
+	for _, t := range tokens {
+		switch {
+		case !t.op || !contains([]string{"&&", "||", ";"}, t.text):
+			words = words || !t.op
+		case words: // ;; or ; at the start doesn't count
+			pipelines = append(pipelines, string(in[start:t.pos]))
+			fallthrough
+		default:
+			start, words = t.pos+len(t.text), false
+		}
+	}
+	if words {
+		pipelines = append(pipelines, string(in[start:]))
+	}
+	return pipelines, list.ops, nil
+}
+
+func parseTokens(tokens []token) (*cmdList, error) {
+	list := &cmdList{}
+	p := &pipeline{}
+	c := &simpleCmd{}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if !t.op {
+			c.args = append(c.args, t.text)
+			continue
+		}
+		switch t.text {
+		case ">", ">>", "<":
+			if i+1 >= len(tokens) || tokens[i+1].op {
+				return nil, fmt.Errorf("syntax error: %s needs a filename", t.text)
+			}
+			i++
+			c.redirs = append(c.redirs, redirect{t.text, tokens[i].text})
+		case "|":
+			if len(c.args) == 0 {
+				return nil, fmt.Errorf("syntax error near |")
+			}
+			p.cmds = append(p.cmds, c)
+			c = &simpleCmd{}
+		default: // && || ;
+			if len(c.args) == 0 {
+				if t.text == ";" && len(p.cmds) == 0 {
+					continue // ;; or ; at the start, nothing to do
+				}
+				return nil, fmt.Errorf("syntax error near %s", t.text)
+			}
+			p.cmds = append(p.cmds, c)
+			list.pipelines = append(list.pipelines, p)
+			list.ops = append(list.ops, t.text)
+			p, c = &pipeline{}, &simpleCmd{}
+		}
+	}
+
+	switch {
+	case len(c.args) > 0:
+		p.cmds = append(p.cmds, c)
+		list.pipelines = append(list.pipelines, p)
+	case len(c.redirs) > 0 || len(p.cmds) > 0:
+		return nil, fmt.Errorf("syntax error: unexpected end of line")
+	case len(list.ops) > 0 && list.ops[len(list.ops)-1] != ";":
+		return nil, fmt.Errorf("syntax error: unexpected end of line")
+	case len(list.ops) > 0:
+		list.ops = list.ops[:len(list.ops)-1] // trailing ;
+	}
+
+	return list, nil
+}
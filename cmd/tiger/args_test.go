@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 	"testing"
 )
@@ -62,3 +63,140 @@ func TestSplitArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestParseLine(t *testing.T) {
+	cmd := func(args ...string) *simpleCmd { return &simpleCmd{args: args} }
+	for _, test := range []struct {
+		input    string
+		expected *cmdList
+	}{
+		{
+			input:    ``,
+			expected: &cmdList{},
+		},
+		{
+			input: `log --oneline | grep fix`,
+			expected: &cmdList{pipelines: []*pipeline{
+				{cmds: []*simpleCmd{cmd("log", "--oneline"), cmd("grep", "fix")}},
+			}},
+		},
+		{
+			input: `diff>my.patch`,
+			expected: &cmdList{pipelines: []*pipeline{
+				{cmds: []*simpleCmd{{args: []string{"diff"}, redirs: []redirect{{">", "my.patch"}}}}},
+			}},
+		},
+		{
+			input: `log >> 'log file' < /dev/null`,
+			expected: &cmdList{pipelines: []*pipeline{
+				{cmds: []*simpleCmd{{args: []string{"log"}, redirs: []redirect{{">>", "log file"}, {"<", "/dev/null"}}}}},
+			}},
+		},
+		{
+			input: `add . && commit -m x || status; fetch;`,
+			expected: &cmdList{
+				pipelines: []*pipeline{
+					{cmds: []*simpleCmd{cmd("add", ".")}},
+					{cmds: []*simpleCmd{cmd("commit", "-m", "x")}},
+					{cmds: []*simpleCmd{cmd("status")}},
+					{cmds: []*simpleCmd{cmd("fetch")}},
+				},
+				ops: []string{"&&", "||", ";"},
+			},
+		},
+		{
+			input: `commit -m "a | b && c" 'd;e' f\|g h & i`,
+			expected: &cmdList{pipelines: []*pipeline{
+				{cmds: []*simpleCmd{cmd("commit", "-m", "a | b && c", "d;e", "f|g", "h", "&", "i")}},
+			}},
+		},
+		{
+			input: `commit -m ''`,
+			expected: &cmdList{pipelines: []*pipeline{
+				{cmds: []*simpleCmd{cmd("commit", "-m", "")}},
+			}},
+		},
+	} {
+		actual, err := parseLine(test.input)
+		if err != nil || !reflect.DeepEqual(test.expected, actual) {
+			fmt.Printf("input:    %#v\n", test.input)
+			fmt.Printf("expected: %#v\n", test.expected)
+			fmt.Printf("actual:   %#v %v\n", actual, err)
+			t.FailNow()
+		}
+	}
+
+	for _, input := range []string{`| grep`, `log |`, `log &&`, `diff >`, `> file`, `a && && b`} {
+		if _, err := parseLine(input); err == nil {
+			t.Fatalf("expected a syntax error for: %#v", input)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Setenv("TIGER_TEST", "a b")
+	t.Setenv("TIGER_EMPTY", "")
+	for _, test := range []struct {
+		input    string
+		expected []string
+	}{
+		{`echo $TIGER_TEST`, []string{"echo", "a b"}},
+		{`echo ${TIGER_TEST}c "$TIGER_TEST" '$TIGER_TEST' \$TIGER_TEST`, []string{"echo", "a bc", "a b", "$TIGER_TEST", "$TIGER_TEST"}},
+		{`echo $TIGER_EMPTY "$TIGER_EMPTY" $ x$`, []string{"echo", "", "$", "x$"}},
+		{`cd ~ ~/src a~ '~'`, []string{"cd", home, home + "/src", "a~", "~"}},
+		{`mkdir -p go/{bin,pkg,src}/`, []string{"mkdir", "-p", "go/bin/", "go/pkg/", "go/src/"}},
+		{`x{a,b{1..3}} {c..a} {1..7..3}`, []string{"xa", "xb1", "xb2", "xb3", "c", "b", "a", "1", "4", "7"}},
+		{`stash@{0} {a} '{a,b}' \{a,b} {a,"b c"}`, []string{"stash@{0}", "{a}", "{a,b}", "{a,b}", "a", "b c"}},
+		{`{a,b}x{1,2}`, []string{"ax1", "ax2", "bx1", "bx2"}},
+		{`echo $1 $# $@`, []string{"echo", "0"}},
+	} {
+		actual := []string{}
+		for _, tok := range lex(test.input) {
+			actual = append(actual, tok.text)
+		}
+		if !reflect.DeepEqual(test.expected, actual) {
+			fmt.Printf("input:    %#v\n", test.input)
+			fmt.Printf("expected: %#v\n", test.expected)
+			fmt.Printf("actual:   %#v\n", actual)
+			t.FailNow()
+		}
+	}
+
+	// in a macro
+	actual := []string{}
+	for _, tok := range lex(`$0 x$1y $# $@ "${2}" $3`, "tidy", "a b", "c") {
+		actual = append(actual, tok.text)
+	}
+	expected := []string{"tidy", "xa by", "2", "a b", "c", "c"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected: %#v\nactual:   %#v", expected, actual)
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	for _, test := range []struct {
+		input     string
+		pipelines []string
+		ops       []string
+	}{
+		{`status`, []string{`status`}, nil},
+		{`; false; log $? | grep "a;b" && export X=1 || log $X;`, []string{` false`, ` log $? | grep "a;b" `, ` export X=1 `, ` log $X`}, []string{";", "&&", "||"}},
+		{`$EMPTY && x\;y 'a && b'`, []string{`$EMPTY `, ` x\;y 'a && b'`}, []string{"&&"}},
+	} {
+		pipelines, ops, err := splitLine(test.input)
+		if err != nil || !reflect.DeepEqual(test.pipelines, pipelines) || !reflect.DeepEqual(test.ops, ops) {
+			fmt.Printf("input:    %#v\n", test.input)
+			fmt.Printf("expected: %#v %#v\n", test.pipelines, test.ops)
+			fmt.Printf("actual:   %#v %#v %v\n", pipelines, ops, err)
+			t.FailNow()
+		}
+	}
+
+	if _, _, err := splitLine(`log && | grep`); err == nil {
+		t.Fatal("expected a syntax error")
+	}
+}
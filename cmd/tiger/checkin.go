@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// checkin runs a sequence of steps, stopping at the first one that fails.
+//
+// everything is configured with git config:
+//
+//     tiger.checkin.steps     default: "stage format test commit push"
+//     tiger.checkin.stage     prompt (default), all, tracked, none
+//     tiger.checkin.format    shell command, e.g. "gofmt -l -w ."
+//     tiger.checkin.test      shell command, e.g. "go test ./..."
+//     tiger.checkin.protected branch which will never be pushed to,
+//                             can be specified multiple times
+//
+// format and test are skipped when they're not configured.
+
+var errSkipped = errors.New("skipped")
+
+type checkinStep struct {
+	name string
+	run  func(ci *checkin) (stdout, stderr string, err error)
+}
+
+func init() {
+	register(&builtin{
+		name:    "checkin",
+		aliases: []string{"ci"},
+		usage:   "ci|checkin [message]",
+		about:   "add, commit and push, see tiger.checkin.* in git config",
+		run: func(ctx *session, args []string, io *stdio) error {
+			return runCheckin(io, strings.Join(args[1:], " "))
+		},
+	})
+}
+
+type checkin struct {
+	io     *stdio
+	msg    string
+	answer string // whatever was typed in reply to "git add . first?"
+}
+
+var checkinSteps = map[string]checkinStep{
+	"stage":  {"stage", (*checkin).stage},
+	"format": {"format", (*checkin).format},
+	"test":   {"test", (*checkin).test},
+	"commit": {"commit", (*checkin).commit},
+	"push":   {"push", (*checkin).push},
+}
+
+func checkinConfigSteps() ([]checkinStep, error) {
+	names, err := config("tiger.checkin.steps")
+	if err != nil || names == "" {
+		names = "stage format test commit push"
+	}
+	steps := []checkinStep{}
+	for _, name := range strings.Fields(names) {
+		step, ok := checkinSteps[name]
+		if !ok {
+			return nil, fmt.Errorf("tiger.checkin.steps: unknown step %q", name)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func runCheckin(io *stdio, msg string) error {
+	steps, err := checkinConfigSteps()
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		if step.name == "push" {
+			if err := checkinGuard(); err != nil {
+				return err
+			}
+		}
+	}
+
+	ci := &checkin{io: io, msg: msg}
+	results := make([]error, len(steps))
+	failed := -1
+	for i, step := range steps {
+		stdout, stderr, err := step.run(ci)
+		results[i] = err
+		if err != nil && err != errSkipped {
+			println(stdout, stderr, err)
+			failed = i
+			break
+		}
+		if step.name == "commit" {
+			println(stdout, stderr, nil)
+		}
+	}
+
+	fmt.Fprintln(io.out)
+	for i, step := range steps {
+		switch {
+		case failed >= 0 && i > failed:
+			fmt.Fprintln(io.out, "[ "+Grey+"--"+Reset+" ] "+step.name)
+		case results[i] == errSkipped:
+			fmt.Fprintln(io.out, "[ "+Grey+"--"+Reset+" ] "+step.name+" (skipped)")
+		case results[i] != nil:
+			fmt.Fprintln(io.out, "["+BgRed+"FAIL"+Reset+"] "+step.name)
+		default:
+			fmt.Fprintln(io.out, "[ "+Green+"OK"+Reset+" ] "+step.name)
+		}
+	}
+
+	if failed >= 0 {
+		return fmt.Errorf("checkin aborted: %s failed", steps[failed].name)
+	}
+	return nil
+}
+
+// refuse to push during a rebase or to a protected branch
+// checked before anything else happens so we don't leave behind a commit
+func checkinGuard() error {
+	dir, err := gitDir()
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		if fileExists(dir + PATH_SEPARATOR + ".git" + PATH_SEPARATOR + name) {
+			return fmt.Errorf("refusing to checkin: rebase in progress")
+		}
+	}
+
+	branch, err := currentBranch()
+	if err != nil {
+		return nil // detached HEAD, let push complain about it
+	}
+	target := pushBranch()
+	protected, _, _ := git("config", "--get-all", "tiger.checkin.protected").Output()
+	for _, p := range strings.Fields(protected) {
+		if p == branch {
+			return fmt.Errorf("refusing to checkin: %s is a protected branch", branch)
+		}
+		if p == target {
+			return fmt.Errorf("refusing to checkin: %s pushes to %s, which is a protected branch", branch, target)
+		}
+	}
+	return nil
+}
+
+// the branch on the remote that push updates, "" if there isn't one
+func pushBranch() string {
+	for _, rev := range []string{"@{push}", "@{u}"} {
+		ref, _, err := git("rev-parse", "--symbolic-full-name", rev).Output()
+		ref = strings.TrimPrefix(strings.TrimSpace(ref), "refs/remotes/")
+		// <remote>/<branch>
+		if _, branch, ok := strings.Cut(ref, "/"); err == nil && ok {
+			return branch
+		}
+	}
+	return ""
+}
+
+func (ci *checkin) stage() (stdout, stderr string, err error) {
+	policy, _ := config("tiger.checkin.stage")
+	switch policy {
+	case "", "prompt":
+		stdout, _, err := git("status", "--porcelain").Output()
+		if err != nil {
+			return "", "", err
+		}
+		for _, ln := range strings.Split(stdout, "\n") {
+			if len(ln) > 0 && ln[0] != ' ' && ln[0] != '?' {
+				// something is already staged
+				return "", "", errSkipped
+			}
+		}
+		fmt.Println(Cyan + "git add ." + Reset + " first? [if you don't type \"no\" I'm going to do it anyway]")
+		if !interactive {
+			// like every other question in a script, tiger.checkin.stage
+			// is how you say yes
+			fmt.Println("no")
+			return "", "", fmt.Errorf("not staging anything")
+		}
+		ci.answer = readLine()
+		if strings.ToLower(ci.answer) == "no" {
+			return "", "", fmt.Errorf("not staging anything")
+		}
+		return git("add", ".").Output()
+	case "all":
+		return git("add", "-A").Output()
+	case "tracked":
+		return git("add", "-u").Output()
+	case "none":
+		return "", "", errSkipped
+	}
+	return "", "", fmt.Errorf("tiger.checkin.stage: unknown policy %q", policy)
+}
+
+func (ci *checkin) format() (stdout, stderr string, err error) {
+	c, err := config("tiger.checkin.format")
+	if err != nil || c == "" {
+		return "", "", errSkipped
+	}
+	// not deleted files, there's nothing to format and git add would complain
+	staged, _, err := git("diff", "--cached", "--name-only", "-z", "--diff-filter=d").Output()
+	if err != nil {
+		return "", "", err
+	}
+	stdout, stderr, err = newCmd("sh", "-c", c).Output()
+	if err != nil {
+		return
+	}
+	// the names are relative to the top of the repository
+	top, err := gitDir()
+	if err != nil {
+		return
+	}
+	files := []string{}
+	for _, name := range strings.Split(staged, "\x00") {
+		if name != "" && fileExists(filepath.Join(top, name)) {
+			files = append(files, name)
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+	// pick up whatever the formatter changed in files that are already staged
+	return git(append([]string{"-C", top, "add", "--"}, files...)...).Output()
+}
+
+func (ci *checkin) test() (stdout, stderr string, err error) {
+	c, err := config("tiger.checkin.test")
+	if err != nil || c == "" {
+		return "", "", errSkipped
+	}
+	return newCmd("sh", "-c", c).Output()
+}
+
+func (ci *checkin) commit() (stdout, stderr string, err error) {
+	msg := ci.msg
+	if msg == "" {
+		msg = ci.answer
+	}
+	if msg == "" {
+		fmt.Println("enter commit message (optional):")
+		msg = readLine()
+	}
+	return git("commit", "--allow-empty", "--allow-empty-message", "-m", msg).Output()
+}
+
+func (ci *checkin) push() (stdout, stderr string, err error) {
+	remotes, _, err := git("remote").Output()
+	if err != nil || strings.TrimSpace(remotes) == "" {
+		return "", "", errSkipped
+	}
+	return "", "", push(ci.io, nil)
+}
+
+func currentBranch() (string, error) {
+	stdout, _, err := git("symbolic-ref", "--short", "-q", "HEAD").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+func hasUpstream() bool {
+	_, _, err := git("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}").Output()
+	return err == nil
+}
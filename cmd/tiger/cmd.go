@@ -2,8 +2,8 @@ package main
 
 import (
 	"io"
-	"os"
 	"os/exec"
+	"time"
 )
 
 type buf []byte
@@ -36,36 +36,35 @@ func (c *cmd) Output() (stdout, stderr string, err error) {
 	o, e := &buf{}, &buf{}
 	c.cmd.Stdout = o
 	c.cmd.Stderr = e
-	err = c.cmd.Run()
+	err = c.run()
 	return o.String(), e.String(), err
 }
 
 func (c *cmd) Attach() (err error) {
+	return c.AttachIO(terminal)
+}
+
+func (c *cmd) AttachIO(std *stdio) (err error) {
 	// shoutouts to bradfitz for a post on golang-nuts from 2012
-	c.cmd.Stdin = os.Stdin
-	c.cmd.Stdout = os.Stdout
-	c.cmd.Stderr = os.Stderr
-	return c.cmd.Run()
+	c.cmd.Stdin = std.in
+	c.cmd.Stdout = std.out
+	c.cmd.Stderr = std.err
+	return c.run()
 }
 
-func (c *cmd) AttachWithPipe(pipe *exec.Cmd) (err error) {
-	r, w := io.Pipe()
-	c.cmd.Stdout = w
-	pipe.Stdin = r
-	pipe.Stdout = os.Stdout
+// same as AttachIO() but also hold on to stderr so we can figure out what went wrong
+func (c *cmd) AttachCapture(std *stdio) (stderr string, err error) {
+	e := &buf{}
+	c.cmd.Stdin = std.in
+	c.cmd.Stdout = std.out
+	c.cmd.Stderr = io.MultiWriter(std.err, e)
+	err = c.run()
+	return e.String(), err
+}
 
-	// NOTE(tso): yes I know about errWriter and stickyErr but I don't remember how to do them
-	err = c.cmd.Start()
-	if err != nil {
-		return err
-	}
-	err = pipe.Start()
-	if err != nil {
-		return err
-	}
-	go func() {
-		c.cmd.Wait()
-		w.Close()
-	}()
-	return pipe.Wait()
+func (c *cmd) run() error {
+	start := time.Now()
+	err := c.cmd.Run()
+	trace(c.cmd.Args, time.Since(start), err)
+	return err
 }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// everything that isn't a git command is a command:
+//
+//	func init() {
+//	    register(&builtin{
+//	        name:  "summary",
+//	        usage: "summary",
+//	        about: "github-style summary of the repository",
+//	        run:   func(ctx *session, args []string, io *stdio) error { ... },
+//	    })
+//	}
+//
+// args[0] is the name the command was invoked with, just like os.Args
+type command interface {
+	Name() string
+	Aliases() []string
+	Usage() string
+	About() string
+	Complete(args []string) []string
+	Run(ctx *session, args []string, io *stdio) error
+}
+
+// where a command reads from and writes to
+type stdio struct {
+	in       io.Reader
+	out, err io.Writer
+}
+
+var terminal = &stdio{os.Stdin, os.Stdout, os.Stderr}
+
+// state that outlives a single command
+type session struct {
+	lastCwd     string
+	gwd         string
+	watch       *watcher
+	changeslast string            // repoState.changes() when the prompt was drawn
+	fileslast   map[string]string // and repoState.files
+	hookslast   string
+	macros      map[string]string
+	depth       int32 // how many macros deep we are
+	state       *repoState
+
+	lastDuration time.Duration
+}
+
+var errExit = errors.New("exit")
+
+type builtin struct {
+	name     string
+	aliases  []string
+	usage    string
+	about    string
+	complete func(args []string) []string
+	run      func(ctx *session, args []string, io *stdio) error
+}
+
+func (b *builtin) Name() string      { return b.name }
+func (b *builtin) Aliases() []string { return b.aliases }
+func (b *builtin) Usage() string     { return b.usage }
+func (b *builtin) About() string     { return b.about }
+
+func (b *builtin) Complete(args []string) []string {
+	if b.complete == nil {
+		return nil
+	}
+	return b.complete(args)
+}
+
+func (b *builtin) Run(ctx *session, args []string, io *stdio) error {
+	return b.run(ctx, args, io)
+}
+
+var (
+	commands    = map[string]command{}
+	commandList = []command{}
+)
+
+func register(c command) {
+	for _, name := range append([]string{c.Name()}, c.Aliases()...) {
+		if _, ok := commands[name]; ok {
+			panic("command registered twice: " + name)
+		}
+		commands[name] = c
+	}
+	commandList = append(commandList, c)
+}
+
+func lookup(name string) (command, bool) {
+	c, ok := commands[name]
+	return c, ok
+}
+
+// all commands, sorted by name
+func listCommands() []command {
+	list := append([]command{}, commandList...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// run a command, or git if there isn't one with that name
+func dispatch(ctx *session, args []string, io *stdio) error {
+	// typing "git <command>" out of habit
+	if len(args) > 0 && args[0] == "git" {
+		args = args[1:]
+	}
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return nil
+	}
+	if c, ok := lookup(args[0]); ok && !aliasWins(args[0]) {
+		return c.Run(ctx, args, io)
+	}
+	if body, ok := ctx.macro(args[0]); ok {
+		return ctx.runMacro(body, args, io)
+	}
+	if isPassthrough(args[0]) {
+		return newCmd(args[0], args[1:]...).AttachIO(io)
+	}
+	// treat all other git commands as usual
+	return git(args...).AttachIO(io)
+}
+
+// git already told you what went wrong, no need to print an exit status
+func isExitErr(err error) bool {
+	switch err.(type) {
+	case *exec.ExitError, macroErr:
+		return true
+	}
+	return false
+}
+
+func init() {
+	register(&builtin{
+		name:    "exit",
+		aliases: []string{"quit"},
+		usage:   "exit|quit",
+		about:   "bye",
+		run: func(ctx *session, args []string, io *stdio) error {
+			return errExit
+		},
+	})
+
+	register(&builtin{
+		name:  "complete",
+		usage: "complete [partial command line]",
+		about: "list what the last word could be completed to",
+		run: func(ctx *session, args []string, io *stdio) error {
+			for _, c := range complete(ctx, args[1:]) {
+				fmt.Fprintln(io.out, c)
+			}
+			return nil
+		},
+	})
+}
+
+// args is a command line split with splitArgs, the last one is what we're completing
+func complete(ctx *session, args []string) []string {
+	if len(args) > 0 && args[0] == "git" {
+		args = args[1:]
+	}
+	if len(args) <= 1 {
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		names := append(aliasNames(), ctx.macroNames()...)
+		for name := range commands {
+			names = append(names, name)
+		}
+		return completeWords(prefix, names)
+	}
+	if c, ok := lookup(args[0]); ok {
+		return c.Complete(args[1:])
+	}
+	return completeFiles(args[len(args)-1], false)
+}
+
+func completeWords(prefix string, words []string) []string {
+	matches := []string{}
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) && !contains(matches, w) {
+			matches = append(matches, w)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func completeFiles(prefix string, dirsOnly bool) []string {
+	matches, _ := filepath.Glob(prefix + "*")
+	files := []string{}
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			m += "/"
+		} else if dirsOnly {
+			continue
+		}
+		files = append(files, normalizePathSeparators(m))
+	}
+	return files
+}
+
+func completeRefs(prefix string) []string {
+	stdout, _, err := git("for-each-ref", "--format=%(refname:short)", "refs/heads", "refs/tags", "refs/remotes").Output()
+	if err != nil {
+		return nil
+	}
+	return completeWords(prefix, strings.Fields(stdout))
+}
+
+// the last argument, or "" if there aren't any
+func lastArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[len(args)-1]
+}
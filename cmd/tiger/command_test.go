@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommands(t *testing.T) {
+	for _, c := range commandList {
+		for _, name := range append([]string{c.Name()}, c.Aliases()...) {
+			if found, ok := lookup(name); !ok || found != c {
+				t.Fatalf("lookup(%q) didn't find %s", name, c.Name())
+			}
+		}
+		if c.Usage() == "" || c.About() == "" {
+			t.Fatalf("%s needs usage and about for help", c.Name())
+		}
+	}
+}
+
+func TestDispatchHelp(t *testing.T) {
+	out := &buf{}
+	err := dispatch(&session{}, []string{"help"}, &stdio{&buf{}, out, out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range commandList {
+		if !strings.Contains(out.String(), c.Usage()) {
+			t.Fatalf("help doesn't list %s:\n%s", c.Name(), out)
+		}
+	}
+
+	err = dispatch(&session{}, []string{"exit"}, &stdio{&buf{}, out, out})
+	if err != errExit {
+		t.Fatalf("expected errExit, got: %v", err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	actual := complete(&session{}, []string{"chec"})
+	if len(actual) != 1 || actual[0] != "checkin" {
+		t.Fatalf("expected [checkin], got: %#v", actual)
+	}
+	actual = complete(&session{}, []string{"hooks", "in"})
+	if len(actual) != 1 || actual[0] != "install" {
+		t.Fatalf("expected [install], got: %#v", actual)
+	}
+}
+
+func TestSafely(t *testing.T) {
+	debugLog = t.TempDir() + "/debug.log"
+	err := safely(func() error {
+		var m map[string]int
+		m["boom"]++
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "stack trace in") {
+		t.Fatalf("expected a recovered error, got: %v", err)
+	}
+}
+
+func TestPipelineRedirect(t *testing.T) {
+	f := t.TempDir() + "/in"
+	if err := os.WriteFile(f, []byte("abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := parseLine("help | wc -c < " + f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &buf{}
+	done := make(chan error, 1)
+	go func() { done <- runPipeline(&session{}, list.pipelines[0], &stdio{&buf{}, out, out}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("help is still waiting for someone to read the pipe")
+	}
+	if strings.TrimSpace(out.String()) != "4" {
+		t.Fatalf("expected wc to read the file, got %q", out)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	exit3 := exec.Command("sh", "-c", "exit 3").Run()
+	if _, ok := exit3.(*exec.ExitError); !ok {
+		t.Skip(exit3)
+	}
+	for _, test := range []struct {
+		err      error
+		expected int
+	}{
+		{nil, 0},
+		{errExit, 0},
+		{exit3, 3},
+		{errors.New("not a git repository"), 1},
+		{macroErr{errors.New("macros nested too deep")}, 1},
+	} {
+		if actual := exitStatus(test.err); actual != test.expected {
+			t.Fatalf("exitStatus(%v) = %d, expected %d", test.err, actual, test.expected)
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	t.Setenv("TIGER_A", "")
+	t.Setenv("TIGER_B", "")
+	script := "# comment\n\n  export TIGER_A=$1\nexit\nexport TIGER_B=x\n"
+	out := &buf{}
+	if err := runScript(&session{}, strings.NewReader(script), &stdio{&buf{}, out, out}, "script", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := os.Getenv("TIGER_A"), os.Getenv("TIGER_B"); a != "v" || b != "" {
+		t.Fatalf("TIGER_A=%q TIGER_B=%q, expected the script to stop at exit", a, b)
+	}
+}
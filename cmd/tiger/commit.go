@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	// feature: draft: edit commit message while staging
+	register(&builtin{
+		name:  "draft",
+		usage: "draft",
+		about: "write the commit message while staging",
+		run: func(ctx *session, args []string, io *stdio) error {
+			draft, err := draftFile()
+			if err != nil {
+				return err
+			}
+			ed, err := editor(draft)
+			if err != nil {
+				return err
+			}
+			return ed.Attach()
+		},
+	})
+
+	register(&builtin{
+		name:  "commit",
+		usage: "commit [-m message to end of line]",
+		about: "-m doesn't need quotes, always --allow-empty-message",
+		run:   commit,
+	})
+}
+
+func commit(ctx *session, args []string, io *stdio) error {
+	draft, err := draftFile()
+	if err == nil {
+		if fileExists(draft) {
+			if len(args) == 1 {
+				msg, err := fileGetContents(draft)
+				if err != nil {
+					return err
+				}
+				err = println(git("commit", "-m", msg).Output())
+				os.Remove(draft)
+				return err
+			}
+			ch := make(chan error, 1)
+			go func() { ch <- println(git("commit", "-t", draft).Output()) }()
+			<-time.After(time.Millisecond * 100)
+			os.Remove(draft)
+			return <-ch
+		}
+	}
+
+	if len(args) == 1 {
+		// standard behavior (open editor, abort due to empty message)
+		return git("commit").Attach()
+	}
+	args = args[1:]
+	flags := []string{}
+here:
+	for n, arg := range args {
+		switch arg {
+		case "-m": // NOTE(tso): -m eats everything to end-of-line and uses it as commit message!
+			// enhanced behavior: accomodate one-liner commit message
+			//     ∗ always --allow-empty-message
+			flags = append(flags, "--allow-empty-message")
+			msg := ""
+			if len(args) > n+1 {
+				msg = strings.Join(args[n+1:], " ")
+			} else {
+				fmt.Println("enter commit message (optional):")
+				msg = readLine()
+			}
+			flags = append(flags, "-m", msg)
+			break here
+		default:
+			flags = append(flags, arg)
+		}
+	}
+	return git(append([]string{"commit"}, flags...)...).AttachIO(io)
+}
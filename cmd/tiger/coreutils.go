@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reinventing coreutils poorly
+
+func init() {
+	register(&builtin{
+		name:  "cd",
+		usage: "cd [dir|-]",
+		about: "change directory, - goes back",
+		complete: func(args []string) []string {
+			return completeFiles(lastArg(args), true)
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			if len(args) < 2 {
+				return nil
+			}
+			cd := strings.Join(args[1:], " ")
+			if cd == "-" {
+				cd = ctx.lastCwd
+			}
+			return ctx.chdir(io, cd)
+		},
+	})
+
+	register(&builtin{
+		name:  "cat",
+		usage: "cat [revision (optional)] [filename]",
+		about: "cat-file blob without having to look up the hash",
+		complete: func(args []string) []string {
+			if len(args) == 1 {
+				return append(completeRefs(args[0]), completeFiles(args[0], false)...)
+			}
+			return completeFiles(lastArg(args), false)
+		},
+		run: cat,
+	})
+
+	register(&builtin{
+		name:  "ls",
+		usage: "ls",
+		about: "files known to git and the contents of the current directory",
+		run:   ls,
+	})
+
+	register(&builtin{
+		name:  "mkdir",
+		usage: "mkdir [dirs]",
+		about: "mkdir -p",
+		complete: func(args []string) []string {
+			return completeFiles(lastArg(args), true)
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			// e.g. mkdir -p go/{bin,pkg,src}/
+			dirs := []string{}
+			for _, arg := range args[1:] {
+				if arg != "-p" {
+					dirs = append(dirs, arg)
+				}
+			}
+			if len(dirs) == 0 {
+				return fmt.Errorf("usage: mkdir [dirs]")
+			}
+			for _, dir := range dirs {
+				if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+
+	register(&builtin{
+		name:  "rm",
+		usage: "rm [git rm flags] [files]",
+		about: "git rm but doesn't fail on .* or untracked/ignored files",
+		complete: func(args []string) []string {
+			return completeFiles(lastArg(args), false)
+		},
+		run: rm,
+	})
+}
+
+func (ctx *session) chdir(io *stdio, dir string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	ctx.lastCwd = cwd
+
+	currentGwd, err := gitDir()
+	if currentGwd != ctx.gwd {
+		ctx.watch.RemoveAll()
+		if err == nil {
+			ctx.gwd = currentGwd
+			go ctx.watch.Watch(ctx.gwd)
+		}
+		ctx.hookslast = hooksNotice()
+		if ctx.hookslast != "" {
+			fmt.Fprintln(io.out, ctx.hookslast)
+		}
+	}
+	return nil
+}
+
+func cat(ctx *session, args []string, std *stdio) error {
+	// TODO(tso): this could use a lot of improvements
+	// - resolve relative filepaths
+	// - make it clear somehow that this is not real cat
+
+	if len(args) < 2 {
+		if std.in != os.Stdin { // e.g. log | cat
+			_, err := io.Copy(std.out, std.in)
+			return err
+		}
+		fmt.Fprintln(std.out, Red+"usage:"+Reset, "cat [branch (optional)] [filename]")
+		return nil
+	}
+
+	var treeish, filename string
+	if len(args) >= 3 {
+		treeish = args[1]
+		filename = strings.Join(args[2:], " ")
+	} else {
+		treeish = head()
+		filename = strings.Join(args[1:], " ")
+	}
+
+	stdout, _, err := git("ls-tree", treeish).Output()
+	if err != nil {
+		if !fileExists(filename) {
+			return fmt.Errorf("file not found: %s", filename)
+		}
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return page(std, f)
+	}
+
+	// we're in a git repository
+	gitFiles := strings.Split(strings.TrimSpace(stdout), "\n")
+	for _, ln := range gitFiles {
+		var (
+			mode              int
+			thing, hash, name string
+		)
+		fmt.Sscanf(ln, "%d %s %s    %s", &mode, &thing, &hash, &name)
+
+		if filename == name {
+			stdout, stderr, err := git("cat-file", thing, hash).Output()
+			if err != nil {
+				return println("", stderr, err)
+			}
+			return page(std, strings.NewReader(stdout))
+		}
+	}
+	return fmt.Errorf("file: %s not found @ revision: %s", filename, treeish)
+}
+
+func ls(ctx *session, args []string, io *stdio) error {
+	// TODO(tso): this could use a lot of improvements
+	// - columns?
+	// - sorting
+	// - list directories first
+	// - merge the two lists and use colors or a [x] to show
+	//   which files are in the index, which are untracked, ignored...
+	// - diff stats
+	stdout, _, err := git("ls-tree", head()).Output()
+	if err == nil {
+		// we're in a git repository
+		gitFiles := strings.Split(strings.TrimSpace(stdout), "\n")
+		for i, ln := range gitFiles {
+			var (
+				mode              int
+				thing, hash, name string
+			)
+			fmt.Sscanf(ln, "%d %s %s    %s", &mode, &thing, &hash, &name)
+			if thing == "tree" {
+				name += "/"
+			} else if thing != "blob" {
+				name = "(" + thing + ") " + name
+			}
+			gitFiles[i] = name
+		}
+		fmt.Fprintln(io.out, "files known to git:")
+		for _, f := range gitFiles {
+			fmt.Fprint(io.out, "\t", f, "\n")
+		}
+		fmt.Fprintln(io.out)
+	}
+
+	files, err := os.ReadDir(".")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(io.out, "current directory contents:")
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() {
+			name += "/"
+		}
+		fmt.Fprint(io.out, "\t", name, "\n")
+	}
+
+	fmt.Fprintln(io.out)
+	return nil
+}
+
+// "improved" git rm
+//   - don't fail just because .* matches . and ..
+//   - don't choke just because [glob pattern] matches untracked or ignored files
+//
+// accomplishing this for now by globbing first
+// and doing each rm one at a time
+// so if one or more operations fail the other operations don't fail
+func rm(ctx *session, args []string, io *stdio) error {
+	flags := []string{}
+	paths := []string{}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "-h", "--help", "-f", "--force", "-n", "-r", "--cached", "--ignore-unmatch", "--quiet", "--":
+			flags = append(flags, arg)
+		default:
+			glob, err := filepath.Glob(arg)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, glob...)
+		}
+	}
+	if len(paths) == 0 {
+		return git(append([]string{"rm"}, flags...)...).AttachIO(io)
+	}
+	for _, path := range paths {
+		git(append([]string{"rm"}, append(flags, path)...)...).AttachIO(io)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GUI editors return immediately unless you tell them not to
+var editorWaitFlags = map[string][]string{
+	"code":          {"--wait", "-w"},
+	"code-insiders": {"--wait", "-w"},
+	"codium":        {"--wait", "-w"},
+	"subl":          {"--wait", "-w"},
+	"sublime_text":  {"--wait", "-w"},
+	"atom":          {"--wait", "-w"},
+	"zed":           {"--wait", "-w"},
+	"mate":          {"-w", "--wait"},
+	"gvim":          {"-f", "--nofork"},
+	"mvim":          {"-f", "--nofork"},
+	"kate":          {"-b", "--block"},
+	"gedit":         {"-s", "--standalone", "--wait"},
+	"notepad++":     {"-multiInst", "-nosession"},
+	"notepad++.exe": {"-multiInst", "-nosession"},
+}
+
+// same order git uses: GIT_EDITOR, core.editor, VISUAL, EDITOR, vi
+func editorCommand() (string, error) {
+	if ed := os.Getenv("GIT_EDITOR"); ed != "" {
+		return ed, nil
+	}
+	if ed, err := config("core.editor"); err == nil && ed != "" {
+		return ed, nil
+	}
+	dumb := os.Getenv("TERM") == "" || os.Getenv("TERM") == "dumb"
+	if ed := os.Getenv("VISUAL"); ed != "" && !dumb {
+		return ed, nil
+	}
+	if ed := os.Getenv("EDITOR"); ed != "" {
+		return ed, nil
+	}
+	if dumb {
+		return "", fmt.Errorf("terminal is dumb, but EDITOR unset")
+	}
+	return "vi", nil
+}
+
+// core.editor can be something like `code --wait` or `vim -c startinsert`
+func editor(files ...string) (*cmd, error) {
+	ed, err := editorCommand()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	for _, arg := range splitArgs(ed) {
+		if arg != "" {
+			args = append(args, arg)
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no editor")
+	}
+
+	name := strings.ToLower(filepath.Base(normalizePathSeparators(args[0])))
+	if flags, ok := editorWaitFlags[name]; ok {
+		wait := true
+		for _, f := range flags {
+			if contains(args[1:], f) {
+				wait = false
+			}
+		}
+		if wait {
+			args = append(args, flags[0])
+		}
+	}
+
+	return newCmd(args[0], append(args[1:], files...)...), nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		env      map[string]string
+		expected []string // nil for an error
+	}{
+		{map[string]string{"TERM": "xterm"}, []string{"vi", "f"}},
+		{map[string]string{"TERM": "dumb"}, nil},
+		{map[string]string{"TERM": "dumb", "VISUAL": "code", "EDITOR": "nano"}, []string{"nano", "f"}},
+		{map[string]string{"TERM": "xterm", "VISUAL": "code", "EDITOR": "nano"}, []string{"code", "--wait", "f"}},
+		{map[string]string{"TERM": "xterm", "VISUAL": "code", "core.editor": "vim -c startinsert"}, []string{"vim", "-c", "startinsert", "f"}},
+		{map[string]string{"TERM": "xterm", "core.editor": "vim", "GIT_EDITOR": "code -w"}, []string{"code", "-w", "f"}},
+		{map[string]string{"TERM": "xterm", "GIT_EDITOR": `"/Applications/Sublime Text.app/bin/subl"`}, []string{"/Applications/Sublime Text.app/bin/subl", "--wait", "f"}},
+		{map[string]string{"TERM": "xterm", "EDITOR": "gedit --standalone"}, []string{"gedit", "--standalone", "f"}},
+	} {
+		testEnv(t, []string{"GIT_EDITOR", "VISUAL", "EDITOR", "TERM"}, test.env)
+		c, err := editor("f")
+		if test.expected == nil {
+			if err == nil {
+				t.Fatalf("%v: expected an error, got %v", test.env, c.cmd.Args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", test.env, err)
+		}
+		if !reflect.DeepEqual(test.expected, c.cmd.Args) {
+			t.Fatalf("%v: expected %q, got %q", test.env, test.expected, c.cmd.Args)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// environment variables set here are seen by $VAR and by everything
+// started from tiger, just like a shell
+func init() {
+	register(&builtin{
+		name:  "export",
+		usage: "export [NAME=value...]",
+		about: "set environment variables, list them without arguments",
+		run: func(ctx *session, args []string, io *stdio) error {
+			if len(args) == 1 {
+				env := os.Environ()
+				sort.Strings(env)
+				for _, kv := range env {
+					fmt.Fprintln(io.out, kv)
+				}
+				return nil
+			}
+			for _, arg := range args[1:] {
+				name, value, ok := strings.Cut(arg, "=")
+				if !ok {
+					// NAME alone, already in the environment or it's nothing
+					continue
+				}
+				if name == "" {
+					return fmt.Errorf("export: invalid name: %q", arg)
+				}
+				if err := os.Setenv(name, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+
+	register(&builtin{
+		name:  "unset",
+		usage: "unset [NAME...]",
+		about: "remove environment variables",
+		complete: func(args []string) []string {
+			names := []string{}
+			for _, kv := range os.Environ() {
+				name, _, _ := strings.Cut(kv, "=")
+				names = append(names, name)
+			}
+			return completeWords(lastArg(args), names)
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			for _, name := range args[1:] {
+				if err := os.Unsetenv(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
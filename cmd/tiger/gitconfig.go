@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+func init() {
+	register(&builtin{
+		name:  "config",
+		usage: "config",
+		about: "with no arguments: pretty-print git config --list",
+		run:   prettyConfig,
+	})
+}
+
+// feature: naked "git config" pretty-prints git config --list
+func prettyConfig(ctx *session, args []string, io *stdio) error {
+	if len(args) != 1 {
+		return git(args...).AttachIO(io)
+	}
+	system, _, _ := git("config", "--list", "--system").Output()
+	global, _, _ := git("config", "--list", "--global").Output()
+	local, _, _ := git("config", "--list", "--local").Output()
+	out := &buf{}
+
+	align := func(input string) string {
+		lines := strings.Split(input, "\n")
+		max := 0
+		for _, ln := range lines {
+			idx := strings.Index(ln, "=")
+			if idx > max {
+				max = idx
+			}
+		}
+		for i, ln := range lines {
+			idx := strings.Index(ln, "=")
+			if idx < 0 {
+				continue
+			}
+			key := ln[:idx]
+			value := ln[idx+1:]
+			lines[i] = key + strings.Repeat(" ", max-len(key)) + " = " + value
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	fmt.Fprintln(out, "system:")
+	fmt.Fprintln(out, align(system))
+	fmt.Fprintln(out, "global:")
+	fmt.Fprintln(out, align(global))
+	fmt.Fprintln(out, "local:")
+	fmt.Fprintln(out, align(local))
+
+	return page(io, out)
+}
+
+// alias.* and tiger.* are looked up for every command, so they're read
+// once per line typed instead of a git config for each lookup
+var configCache struct {
+	sync.Mutex
+	values map[string][]string // key -> every value, in git config order
+}
+
+// keys are what git config prints, see configKey()
+func cachedConfig() map[string][]string {
+	configCache.Lock()
+	defer configCache.Unlock()
+	if configCache.values != nil {
+		return configCache.values
+	}
+	values := map[string][]string{}
+	stdout, _, err := git("config", "-z", "--get-regexp", `^(alias|tiger)\.`).Output()
+	if err == nil { // exits 1 when there aren't any
+		for _, kv := range strings.Split(stdout, "\x00") {
+			if key, value, _ := strings.Cut(kv, "\n"); key != "" {
+				values[key] = append(values[key], value)
+			}
+		}
+	}
+	configCache.values = values
+	return values
+}
+
+// like configOK() but from the cache, the last one wins like it does for git
+func cachedConfigOK(key string) (string, bool) {
+	values := cachedConfig()[configKey(key)]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// what git config would print for key: tiger.macro.Hi is tiger.macro.hi
+func configKey(key string) string {
+	first, last := strings.Index(key, "."), strings.LastIndex(key, ".")
+	if first < 0 {
+		return strings.ToLower(key)
+	}
+	return strings.ToLower(key[:first]) + key[first:last] + strings.ToLower(key[last:])
+}
+
+// someone may have changed something since the last line
+func forgetConfig() {
+	configCache.Lock()
+	configCache.values = nil
+	configCache.Unlock()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// asks one long running git check-ignore whether paths are ignored, instead
+// of starting git for every file that changes. it knows about everything git
+// does: .gitignore files, .git/info/exclude and core.excludesFile
+type ignoreChecker struct {
+	mu     sync.Mutex
+	dir    string
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	out    *bufio.Reader
+	cache  map[string]bool
+	closed bool // we've moved on to another repository, don't start again
+}
+
+func newIgnoreChecker(dir string) *ignoreChecker {
+	return &ignoreChecker{dir: dir, cache: map[string]bool{}}
+}
+
+func (c *ignoreChecker) start() error {
+	// -v -n: an answer for every path, not just the ignored ones
+	cmd := exec.Command("git", "check-ignore", "--stdin", "-z", "-v", "-n")
+	cmd.Dir = c.dir
+	cmd.Env = append(os.Environ(), "GIT_FLUSH=1")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	c.cmd, c.in, c.out = cmd, in, bufio.NewReader(out)
+	return nil
+}
+
+func (c *ignoreChecker) stop() {
+	if c.cmd == nil {
+		return
+	}
+	c.in.Close()
+	c.cmd.Wait()
+	c.cmd, c.in, c.out = nil, nil, nil
+}
+
+// not ignored if we can't tell
+func (c *ignoreChecker) ignored(path string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ignored, ok := c.cache[path]; ok {
+		return ignored
+	}
+	if c.closed {
+		return false
+	}
+	if c.cmd == nil {
+		if err := c.start(); err != nil {
+			return false
+		}
+	}
+	// it dies on paths outside the repository, the next one starts it again
+	if _, err := io.WriteString(c.in, path+"\x00"); err != nil {
+		c.stop()
+		return false
+	}
+	ignored, err := readCheckIgnore(c.out)
+	if err != nil {
+		c.stop()
+		return false
+	}
+	if len(c.cache) > 10000 {
+		c.cache = map[string]bool{}
+	}
+	c.cache[path] = ignored
+	return ignored
+}
+
+// one answer from check-ignore -z -v -n: source, line number, pattern and
+// path. the pattern is empty when nothing matched and starts with ! when
+// the path was ignored and then unignored
+func readCheckIgnore(out *bufio.Reader) (bool, error) {
+	fields := make([]string, 4)
+	for i := range fields {
+		f, err := out.ReadString(0)
+		if err != nil {
+			return false, err
+		}
+		fields[i] = strings.TrimSuffix(f, "\x00")
+	}
+	pattern := fields[2]
+	return pattern != "" && !strings.HasPrefix(pattern, "!"), nil
+}
+
+// a .gitignore changed, git check-ignore only reads them once
+func (c *ignoreChecker) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop()
+	c.cache = map[string]bool{}
+}
+
+func (c *ignoreChecker) close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop()
+	c.closed = true
+}
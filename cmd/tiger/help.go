@@ -0,0 +1,143 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// help pages for git commands, less verbose than the man pages and examples first
+//
+// you can add your own (or replace these) by putting [command].txt files in
+// ~/.tiger/help or wherever tiger.helpDir points to
+
+//go:embed help/*.txt
+var helpPages embed.FS
+
+func init() {
+	register(&builtin{
+		name:  "help",
+		usage: "help [command]",
+		about: "this, or a cheat sheet for a git command",
+		complete: func(args []string) []string {
+			names := append(helpTopics(), aliasNames()...)
+			names = append(names, configMacroNames()...)
+			for _, c := range commandList {
+				names = append(names, c.Name())
+			}
+			return completeWords(lastArg(args), names)
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			return help(ctx, io, args[1:])
+		},
+	})
+}
+
+func helpDir() string {
+	if dir, err := config("tiger.helpDir"); err == nil && dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tiger", "help")
+}
+
+func helpPage(name string) (string, bool) {
+	if dir := helpDir(); dir != "" {
+		if b, err := os.ReadFile(filepath.Join(dir, name+".txt")); err == nil {
+			return string(b), true
+		}
+	}
+	b, err := helpPages.ReadFile("help/" + name + ".txt")
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+func helpTopics() []string {
+	topics := map[string]bool{}
+	entries, _ := helpPages.ReadDir("help")
+	if dir := helpDir(); dir != "" {
+		if files, err := os.ReadDir(dir); err == nil {
+			entries = append(entries, files...)
+		}
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".txt") {
+			topics[strings.TrimSuffix(e.Name(), ".txt")] = true
+		}
+	}
+	names := []string{}
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func help(ctx *session, io *stdio, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(io.out, "tiger commands (everything else goes to git):")
+		fmt.Fprintln(io.out)
+		list := listCommands()
+		tw := 0
+		for _, c := range list {
+			if len(c.Usage()) > tw {
+				tw = len(c.Usage())
+			}
+		}
+		for _, c := range list {
+			fmt.Fprintf(io.out, "    %s%s%s%s  %s\n", Cyan, c.Usage(), Reset, strings.Repeat(" ", tw-len(c.Usage())), c.About())
+		}
+		if macros := ctx.macroNames(); len(macros) > 0 {
+			fmt.Fprintln(io.out)
+			fmt.Fprintln(io.out, "macros:", strings.Join(macros, " "))
+		}
+		if aliases := aliasNames(); len(aliases) > 0 {
+			fmt.Fprintln(io.out)
+			fmt.Fprintln(io.out, "git aliases:", strings.Join(aliases, " "))
+		}
+		fmt.Fprintln(io.out)
+		fmt.Fprintln(io.out, "help [command] for:", strings.Join(helpTopics(), " "))
+		fmt.Fprintln(io.out, "[command] --help for git's own documentation")
+		return nil
+	}
+
+	name := args[0]
+	if _, isCommand := lookup(name); !isCommand {
+		if body, isMacro := ctx.macro(name); isMacro {
+			fmt.Fprintln(io.out, Cyan+name+Reset, "(macro)")
+			fmt.Fprintln(io.out, "    "+body)
+			return nil
+		}
+	}
+	if expansion, isAlias := gitAliases()[name]; isAlias {
+		fmt.Fprintln(io.out, Cyan+name+Reset, "(git alias)")
+		fmt.Fprintln(io.out, "    "+expansion+aliasShadowed(name))
+		if _, isCommand := lookup(name); !isCommand || aliasWins(name) {
+			return nil
+		}
+		fmt.Fprintln(io.out)
+	}
+	page, ok := helpPage(name)
+	if c, isCommand := lookup(name); isCommand {
+		fmt.Fprintln(io.out, Cyan+c.Usage()+Reset, "(tiger)")
+		fmt.Fprintln(io.out, "    "+c.About())
+		if ok {
+			fmt.Fprintln(io.out)
+		}
+		fmt.Fprint(io.out, page)
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("no help for: %s (try: %s --help)", name, name)
+	}
+	fmt.Fprint(io.out, page)
+	return nil
+}
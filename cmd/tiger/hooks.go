@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// hooks: keep .git/hooks up-to-date with a versioned .githooks/ folder
+//
+// either by copying (the default) or by pointing core.hooksPath at .githooks,
+// which can be made the default with:
+//
+//     git config tiger.hooks.install path
+
+const (
+	hookInstalled = "installed"
+	hookDiffers   = "differs"
+	hookMissing   = "not installed"
+)
+
+type hook struct {
+	name            string
+	tracked, active string // paths
+	state           string
+}
+
+func hooksDirs() (tracked, active string, err error) {
+	dir, err := gitDir()
+	if err != nil {
+		return "", "", err
+	}
+	return dir + PATH_SEPARATOR + ".githooks", dir + PATH_SEPARATOR + ".git" + PATH_SEPARATOR + "hooks", nil
+}
+
+// core.hooksPath is already pointing at .githooks
+func hooksPathInstalled() bool {
+	p, err := config("core.hooksPath")
+	return err == nil && strings.TrimRight(normalizePathSeparators(p), "/") == ".githooks"
+}
+
+func hooksList() ([]hook, error) {
+	tracked, active, err := hooksDirs()
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(tracked)
+	if err != nil {
+		return nil, err
+	}
+	hooksPath := hooksPathInstalled()
+	hooks := []hook{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		h := hook{
+			name:    f.Name(),
+			tracked: tracked + PATH_SEPARATOR + f.Name(),
+			active:  active + PATH_SEPARATOR + f.Name(),
+		}
+		switch {
+		case hooksPath:
+			h.state = hookInstalled
+		case !fileExists(h.active):
+			h.state = hookMissing
+		case !sameContents(h.tracked, h.active):
+			h.state = hookDiffers
+		default:
+			h.state = hookInstalled
+		}
+		hooks = append(hooks, h)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].name < hooks[j].name })
+	return hooks, nil
+}
+
+func sameContents(a, b string) bool {
+	ac, aerr := fileGetContents(a)
+	bc, berr := fileGetContents(b)
+	return aerr == nil && berr == nil && ac == bc
+}
+
+// one line for the prompt when .githooks and .git/hooks disagree
+// empty when there's nothing to say
+func hooksNotice() string {
+	hooks, err := hooksList()
+	if err != nil {
+		return ""
+	}
+	names := []string{}
+	for _, h := range hooks {
+		if h.state != hookInstalled {
+			names = append(names, h.name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return Yellow + ".githooks:" + Reset + " " + strings.Join(names, " ") + " not up-to-date (see: hooks status)"
+}
+
+func init() {
+	register(&builtin{
+		name:  "hooks",
+		usage: "hooks [status|diff|install [--copy|--path]|uninstall]",
+		about: "keep .git/hooks up-to-date with .githooks/",
+		complete: func(args []string) []string {
+			return completeWords(lastArg(args), []string{"status", "diff", "install", "uninstall"})
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			err := hooksCmd(io, args[1:])
+			ctx.hookslast = hooksNotice()
+			return err
+		},
+	})
+}
+
+func hooksCmd(io *stdio, args []string) error {
+	sub := "status"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	hooks, err := hooksList()
+	if err != nil {
+		return fmt.Errorf("no .githooks directory")
+	}
+
+	switch sub {
+	case "status":
+		if hooksPathInstalled() {
+			fmt.Fprintln(io.out, "core.hooksPath = .githooks")
+		}
+		for _, h := range hooks {
+			color := Green
+			if h.state != hookInstalled {
+				color = Red
+			}
+			fmt.Fprintln(io.out, color+h.state+Reset, h.name)
+		}
+
+	case "diff":
+		for _, h := range hooks {
+			switch h.state {
+			case hookDiffers:
+				git("diff", "--no-index", h.active, h.tracked).AttachIO(io)
+			case hookMissing:
+				fmt.Fprintln(io.out, Red+h.state+":"+Reset, h.name)
+			}
+		}
+
+	case "install":
+		method, _ := config("tiger.hooks.install")
+		if len(args) > 1 {
+			method = strings.TrimLeft(args[1], "-")
+		}
+		if method == "path" {
+			return println(git("config", "core.hooksPath", ".githooks").Output())
+		}
+		for _, h := range hooks {
+			if h.state == hookInstalled {
+				continue
+			}
+			if h.state == hookDiffers && !confirm("overwrite .git/hooks/"+h.name+"?") {
+				continue
+			}
+			contents, err := fileGetContents(h.tracked)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(h.active, []byte(contents), 0755); err != nil {
+				return err
+			}
+			// WriteFile doesn't change the mode of an existing file
+			if err := os.Chmod(h.active, 0755); err != nil {
+				return err
+			}
+			fmt.Fprintln(io.out, Green+"installed:"+Reset, h.name)
+		}
+
+	case "uninstall":
+		if hooksPathInstalled() {
+			return println(git("config", "--unset", "core.hooksPath").Output())
+		}
+		for _, h := range hooks {
+			if h.state == hookMissing {
+				continue
+			}
+			if h.state == hookDiffers && !confirm(".git/hooks/"+h.name+" has been changed. remove anyway?") {
+				continue
+			}
+			if err := os.Remove(h.active); err != nil {
+				return err
+			}
+			fmt.Fprintln(io.out, Red+"removed:"+Reset, h.name)
+		}
+
+	default:
+		return fmt.Errorf("usage: hooks [status|diff|install [--copy|--path]|uninstall]")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	// feature: keep: ignore a subdir's contents but keep the dir in the working tree
+	register(&builtin{
+		name:  "keep",
+		usage: "keep [dir]",
+		about: "ignore a directory's contents but keep the directory",
+		complete: func(args []string) []string {
+			return completeFiles(lastArg(args), true)
+		},
+		run: keep,
+	})
+
+	// feature: ignore/unignore: add lines to .gitignore
+	register(&builtin{
+		name:  "ignore",
+		usage: "ignore [patterns]",
+		about: "add patterns to .gitignore",
+		complete: func(args []string) []string {
+			return completeFiles(lastArg(args), false)
+		},
+		run: ignore,
+	})
+	register(&builtin{
+		name:  "unignore",
+		usage: "unignore [patterns]",
+		about: "add !patterns to .gitignore",
+		run: func(ctx *session, args []string, io *stdio) error {
+			unignore := []string{args[0]}
+			for _, arg := range args[1:] {
+				unignore = append(unignore, "!"+arg)
+			}
+			return ignore(ctx, unignore, io)
+		},
+	})
+}
+
+func keep(ctx *session, args []string, io *stdio) error {
+	if len(args) != 2 {
+		return fmt.Errorf("keep takes exactly 1 argument")
+	}
+
+	// make sure we're in a git repo
+	_, err := gitDir()
+	if err != nil {
+		return fmt.Errorf("keep only works in a git repo!!")
+	}
+
+	dir := strings.TrimRight(args[1], "\\/")
+	if !fileExists(dir) {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	if !isDir(dir) {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	keepFile := dir + PATH_SEPARATOR + ".keep"
+	if !fileExists(keepFile) {
+		f, err := os.Create(keepFile)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+
+	f, abspath, err := ignoreFile()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(f, dir+"/\n!"+dir+"/.keep\n")
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return git("add", "-f", keepFile, abspath).AttachIO(io)
+}
+
+func ignore(ctx *session, args []string, io *stdio) error {
+	f, abspath, err := ignoreFile()
+	if err != nil {
+		return err
+	}
+	for _, arg := range args[1:] {
+		if _, err := fmt.Fprint(f, arg+"\n"); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	f.Close()
+	return git("add", abspath).AttachIO(io)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// everything that can happen while tiger sits at the prompt. only the
+// goroutine in main() acts on them, everyone else just posts
+type loopEvent interface{}
+
+type lineEvent struct {
+	line string
+	eof  bool
+	err  error
+}
+
+// something changed in the working tree or .git, there's only ever one
+// of these in flight no matter how many files changed
+type changeEvent struct{}
+
+type watchErrEvent struct{ err error }
+
+type fetchEvent struct{ summary string }
+
+type signalEvent struct {
+	sig os.Signal
+	t   time.Time
+}
+
+type eventLoop struct {
+	events  chan loopEvent
+	want    chan struct{} // ask the reader for one more line
+	scanner *bufio.Scanner
+	changed int32 // a changeEvent is waiting to be picked up
+
+	mu      sync.Mutex // asked, eof and later
+	asked   bool       // the reader is busy getting us a line
+	eof     bool
+	later   []loopEvent // came in while a command was reading input
+	reading sync.Mutex  // one readLine() at a time
+}
+
+func newEventLoop(in io.Reader) *eventLoop {
+	l := &eventLoop{
+		events:  make(chan loopEvent, 16),
+		want:    make(chan struct{}, 1),
+		scanner: bufio.NewScanner(in),
+	}
+	go l.read()
+	return l
+}
+
+// stdin is only read when someone asked for a line, that way children
+// like the editor or add -p get the terminal to themselves
+func (l *eventLoop) read() {
+	for range l.want {
+		if !l.scanner.Scan() {
+			l.events <- lineEvent{eof: true, err: l.scanner.Err()}
+			return
+		}
+		l.events <- lineEvent{line: l.scanner.Text()}
+	}
+}
+
+func (l *eventLoop) ask() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.asked && !l.eof {
+		l.asked = true
+		l.want <- struct{}{}
+	}
+}
+
+// anything that came in is a line we have to ask for again
+func (l *eventLoop) got(e loopEvent) loopEvent {
+	switch e := e.(type) {
+	case lineEvent:
+		l.mu.Lock()
+		l.asked = false
+		l.eof = e.eof
+		l.mu.Unlock()
+	case changeEvent:
+		atomic.StoreInt32(&l.changed, 0)
+	}
+	return e
+}
+
+func (l *eventLoop) post(e loopEvent) {
+	l.events <- e
+}
+
+// for the watcher, as many as you like
+func (l *eventLoop) change() {
+	if atomic.CompareAndSwapInt32(&l.changed, 0, 1) {
+		l.events <- changeEvent{}
+	}
+}
+
+// wait for whatever happens next at the prompt
+func (l *eventLoop) next() loopEvent {
+	l.mu.Lock()
+	if len(l.later) > 0 {
+		e := l.later[0]
+		l.later = l.later[1:]
+		l.mu.Unlock()
+		return e
+	}
+	eof := l.eof
+	l.mu.Unlock()
+	if eof {
+		return lineEvent{eof: true}
+	}
+	l.ask()
+	return l.got(<-l.events)
+}
+
+// a line for a command that's asking a question. changes are dropped
+// since the prompt is drawn fresh once the command is done, ^C leaves
+// like it does at the prompt and the rest waits until then
+func (l *eventLoop) readLine() (string, bool) {
+	l.reading.Lock()
+	defer l.reading.Unlock()
+	for {
+		l.mu.Lock()
+		eof := l.eof
+		l.mu.Unlock()
+		if eof {
+			return "", false
+		}
+		l.ask()
+		switch e := l.got(<-l.events).(type) {
+		case lineEvent:
+			return e.line, !e.eof
+		case changeEvent:
+		case signalEvent:
+			bye()
+		default:
+			l.mu.Lock()
+			l.later = append(l.later, e)
+			l.mu.Unlock()
+		}
+	}
+}
+
+// for great justice
+func bye() {
+	fmt.Println()
+	os.Exit(0)
+}
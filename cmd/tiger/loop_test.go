@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEventLoop(t *testing.T) {
+	l := newEventLoop(strings.NewReader("status\ny\nlog\n"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); l.change() }()
+	}
+	wg.Wait()
+	if _, ok := l.next().(changeEvent); !ok {
+		t.Fatal("expected a change")
+	}
+
+	if e, ok := l.next().(lineEvent); !ok || e.line != "status" {
+		t.Fatalf("expected status, got %#v", e)
+	}
+
+	// a command asking a question while things happen
+	l.post(fetchEvent{"news"})
+	l.change()
+	if line, ok := l.readLine(); !ok || line != "y" {
+		t.Fatalf("readLine() = %q, %v", line, ok)
+	}
+	if e, ok := l.next().(fetchEvent); !ok || e.summary != "news" {
+		t.Fatalf("expected the fetch to wait, got %#v", e)
+	}
+	l.change()
+	if _, ok := l.next().(changeEvent); !ok {
+		t.Fatal("changes after readLine() got lost")
+	}
+
+	if e, ok := l.next().(lineEvent); !ok || e.line != "log" {
+		t.Fatalf("expected log, got %#v", e)
+	}
+	if e, ok := l.next().(lineEvent); !ok || !e.eof {
+		t.Fatalf("expected eof, got %#v", e)
+	}
+	if line, ok := l.readLine(); ok || line != "" {
+		t.Fatalf("readLine() after eof = %q, %v", line, ok)
+	}
+}
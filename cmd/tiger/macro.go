@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// macros are command lines with $1 $2 ... $@ $# in them, run like commands:
+//
+//	git config --global tiger.macro.tidy 'ignore *.log && keep build && ci chore: $@'
+//
+// or in ~/.tigerrc, which is run at startup, one command per line:
+//
+//	# comments start with #
+//	macro tidy 'ignore *.log && keep build && ci chore: $@'
+//	export GIT_MERGE_AUTOEDIT=no
+//
+// single quotes so the $ isn't expanded when the macro is defined
+
+// a macro can run other macros, but not forever
+const maxMacroDepth = 64
+
+// runLine already printed whatever went wrong
+type macroErr struct{ error }
+
+func init() {
+	register(&builtin{
+		name:  "macro",
+		usage: "macro [name [command line]]",
+		about: "list, show or define macros for this session",
+		complete: func(args []string) []string {
+			if len(args) > 1 {
+				return nil
+			}
+			return completeWords(lastArg(args), configMacroNames())
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			switch len(args) {
+			case 1:
+				for _, name := range ctx.macroNames() {
+					body, _ := ctx.macro(name)
+					fmt.Fprintln(io.out, Cyan+name+Reset+" = "+body)
+				}
+				return nil
+			case 2:
+				body, ok := ctx.macro(args[1])
+				if !ok {
+					return fmt.Errorf("no such macro: %s", args[1])
+				}
+				fmt.Fprintln(io.out, body)
+				return nil
+			}
+			name := args[1]
+			if _, ok := lookup(name); ok {
+				return fmt.Errorf("macro: %s is a tiger command", name)
+			}
+			if ctx.macros == nil {
+				ctx.macros = map[string]string{}
+			}
+			ctx.macros[name] = strings.Join(args[2:], " ")
+			return nil
+		},
+	})
+}
+
+// ones defined with the macro command take precedence over git config
+func (ctx *session) macro(name string) (string, bool) {
+	if body, ok := ctx.macros[name]; ok {
+		return body, true
+	}
+	return cachedConfigOK("tiger.macro." + name)
+}
+
+func configMacroNames() []string {
+	names := []string{}
+	for key := range cachedConfig() {
+		if name := strings.TrimPrefix(key, "tiger.macro."); name != "" && name != key {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (ctx *session) macroNames() []string {
+	names := configMacroNames()
+	if ctx != nil {
+		for name := range ctx.macros {
+			if !contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// args[0] is the macro's name
+func (ctx *session) runMacro(body string, args []string, std *stdio) error {
+	if atomic.AddInt32(&ctx.depth, 1) > maxMacroDepth {
+		atomic.AddInt32(&ctx.depth, -1)
+		return fmt.Errorf("%s: macros nested too deep", args[0])
+	}
+	defer atomic.AddInt32(&ctx.depth, -1)
+
+	err := runLine(ctx, body, std, args...)
+	if err != nil && err != errExit && !isExitErr(err) {
+		return macroErr{err}
+	}
+	return err
+}
+
+// ~/.tigerrc
+func (ctx *session) runRC() error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(home, ".tigerrc"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runScript(ctx, f, terminal) // errors were printed as they happened
+	return nil
+}
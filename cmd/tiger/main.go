@@ -3,7 +3,6 @@ TODO(tso):
  - show MERGING REVERTING etc in prompt
  - add command "abort" as shorthand for merge --abort, revert --abort ...
  - add command (think of a name) for "git cat-file blob [hash of file@revision] > file"
- - interactively setup remotes when push/pull fails
  - stage: interactive staging
     ONE-BY-ONE: yes | git stage
        -OR-
@@ -85,18 +84,16 @@ TODO(tso): special-case submodules
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path"
 	"path/filepath"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -108,18 +105,6 @@ func git(args ...string) *cmd {
 	return newCmd("git", args...)
 }
 
-func pager() *exec.Cmd {
-	p, err := config("core.pager")
-	checkErr(err)
-	// NOTE(tso): core.pager can have any arbitrary shell syntax
-	//            e.g.(mine right now): diff-so-fancy | less -RFX
-	//            rather than try to reinvent bash just to be able to
-	//            create an epic Pipe() abstraction
-	//            let's just do this for now, consequences be damned:
-	// -tso 2018-08-03 00:59:23a
-	return exec.Command("sh", "-c", "cat - | "+p)
-}
-
 // overriding built-in functions because I can't think of a better name
 func println(stdout, stderr string, err error) error {
 	if err != nil {
@@ -138,6 +123,53 @@ func println(stdout, stderr string, err error) error {
 	return err
 }
 
+var debugLog = filepath.Join(os.TempDir(), "tiger-debug.log")
+
+// keep the session alive when something panics, but leave a stack trace behind
+func recoverErr(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	f, ferr := os.OpenFile(debugLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if ferr != nil {
+		*err = fmt.Errorf("%v", r)
+		return
+	}
+	fmt.Fprintf(f, "%s panic: %v\n%s\n", time.Now().Format(time.RFC3339), r, debug.Stack())
+	f.Close()
+	*err = fmt.Errorf("%v (stack trace in %s)", r, debugLog)
+}
+
+func safely(f func() error) (err error) {
+	defer recoverErr(&err)
+	return f()
+}
+
+// where lines come from at the prompt, set up in main()
+var input *eventLoop
+
+// read a line from stdin while a command is running
+func readLine() string {
+	if !interactive || input == nil {
+		fmt.Println()
+		return ""
+	}
+	line, _ := input.readLine()
+	return line
+}
+
+// yes unless you say no
+func confirm(question string) bool {
+	if !interactive {
+		fmt.Println(question, "[Y/n] n")
+		return false
+	}
+	fmt.Print(question, " [Y/n] ")
+	answer := strings.ToLower(strings.TrimSpace(readLine()))
+	return answer != "n" && answer != "no"
+}
+
 func gitDir() (string, error) {
 	stdout, _, err := git("rev-parse", "--show-toplevel").Output()
 	if err != nil {
@@ -154,6 +186,12 @@ func config(param string) (string, error) {
 	return strings.TrimSpace(stdout), nil
 }
 
+// like os.LookupEnv, so you can tell unset and empty apart
+func configOK(param string) (string, bool) {
+	value, err := config(param)
+	return value, err == nil
+}
+
 func draftFile() (string, error) {
 	dir, err := gitDir()
 	if err != nil {
@@ -168,8 +206,10 @@ func ignoreFile() (*os.File, string, error) {
 		return nil, "", err
 	}
 	path := dir + PATH_SEPARATOR + ".gitignore"
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND, os.ModePerm)
-	checkErr(err)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, "", err
+	}
 	return f, path, nil
 }
 
@@ -179,22 +219,33 @@ func head() string {
 	if err != nil {
 		return ""
 	}
+	return headIn(dir)
+}
 
-	head := fileGetContents(dir + PATH_SEPARATOR + ".git" + PATH_SEPARATOR + "HEAD")
-	checkErr(err)
+// same as head() when you already know where the repository is
+func headIn(dir string) string {
+	defer profileSegment("head")()
+	head, err := fileGetContents(dir + PATH_SEPARATOR + ".git" + PATH_SEPARATOR + "HEAD")
+	if err != nil {
+		return ""
+	}
 
 	if strings.HasPrefix(head, "ref: refs/heads/") {
 		return strings.TrimSpace(strings.TrimPrefix(head, "ref: refs/heads/"))
 	}
 
 	stdout, _, err := git("rev-parse", "--symbolic-full-name", "HEAD").Output()
-	checkErr(err)
+	if err != nil {
+		return ""
+	}
 
 	revParse := strings.TrimSpace(stdout)
 
 	if revParse == "HEAD" {
 		stdout, _, err = git("name-rev", "HEAD").Output()
-		checkErr(err)
+		if err != nil {
+			return ""
+		}
 
 		nameRev := strings.TrimSpace(stdout)
 		nameRev = strings.TrimPrefix(nameRev, "HEAD ")
@@ -206,11 +257,16 @@ func head() string {
 	return revParse
 }
 
-func status() {
-	stat, _, err := git("status", "--porcelain").Output()
+// what to print, and a one line summary of each file for statusDelta()
+//
+// numstat is git diff --numstat, which the caller already has
+func status(numstat string) (string, map[string]string) {
+	defer profileSegment("status")()
+	// no optional locks: don't touch the index, the watcher would see that
+	stat, _, err := git("--no-optional-locks", "status", "--porcelain").Output()
 	stat = strings.TrimSuffix(stat, "\n")
 	if err != nil || stat == "" { // (not a git repo) or "on working directory clean"
-		return
+		return "", nil
 	}
 
 	type statusDiff struct {
@@ -230,15 +286,17 @@ func status() {
 
 		diff := statusDiff{
 			renamed:   x == 'R',
-			deleted:   x == 'D',
 			untracked: x == '?' || y == '?',
 			ignored:   x == '!' || y == '!',
 		}
 
+		// D in the index or D in the working tree, they're not the same file
 		if x == '?' || y != ' ' {
+			diff.deleted = y == 'D'
 			unstaged[name] = diff
 		}
 		if x != '?' && x != ' ' {
+			diff.deleted = x == 'D'
 			staged[name] = diff
 		}
 	}
@@ -262,11 +320,7 @@ func status() {
 	}
 
 	if len(unstaged) > 0 {
-		diff, _, err := git("diff", "--numstat").Output()
-		if err == nil {
-			diff = strings.TrimSuffix(diff, "\n")
-			unstaged = parseDiff(diff, unstaged)
-		}
+		unstaged = parseDiff(numstat, unstaged)
 	}
 
 	if len(staged) > 0 {
@@ -286,7 +340,9 @@ func status() {
 		return names
 	}
 
-	println := func(color, delcolor, name string, s statusDiff) {
+	out := &strings.Builder{}
+	files := map[string]string{}
+	println := func(color, delcolor, name string, s statusDiff) string {
 		diff := ""
 		if !(s.plus == 0 && s.minus == 0) {
 			diff = fmt.Sprintf("%s+%d%s/%s-%d%s", Green, s.plus, Reset, Red, s.minus, Reset)
@@ -297,30 +353,91 @@ func status() {
 		if s.untracked {
 			color = "untracked: " + color
 		}
-		fmt.Println(color+name+Reset, diff)
+		fmt.Fprintln(out, color+name+Reset, diff)
+		return strings.TrimSpace(name + " " + diff)
 	}
 
 	for _, name := range sortMapKeys(staged) {
-		println(Green, Red, name, staged[name])
+		files["staged: "+name] = "staged: " + println(Green, Red, name, staged[name])
 	}
 
 	for _, name := range sortMapKeys(unstaged) {
-		println(Black+BgGrey, BgRed, name, unstaged[name])
+		line := println(Black+BgGrey, BgRed, name, unstaged[name])
+		switch s := unstaged[name]; {
+		case s.untracked:
+			files[name] = Green + "+ " + Reset + line
+		case s.deleted:
+			files[name] = Red + "- " + Reset + line
+		default:
+			files[name] = Yellow + "~ " + Reset + line
+		}
 	}
+	return out.String(), files
 }
 
-func prompt() {
+// what changed since the last time the status was printed, one line per file:
+//
+//	~ main.go +3/-1
+//	+ new_file.go
+//	- removed.go
+//	= reverted.go
+//	staged: x.go +1/-0
+//	unstaged: y.go
+func statusDelta(before, after map[string]string) []string {
+	names := []string{}
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	delta := []string{}
+	for _, name := range names {
+		line, ok := after[name]
+		switch {
+		case !ok && strings.HasPrefix(name, "staged: "):
+			// otherwise it was committed, the prompt says so
+			if file := strings.TrimPrefix(name, "staged: "); after[file] != "" {
+				delta = append(delta, Grey+"unstaged: "+Reset+file)
+			}
+		case !ok && after["staged: "+name] != "":
+			// staged, the staged: line says so
+		case !ok:
+			delta = append(delta, Grey+"= "+Reset+name)
+		case line != before[name]:
+			delta = append(delta, line)
+		}
+	}
+	return delta
+}
+
+func prompt(ctx *session) {
+	drawPrompt(ctx, true)
+}
+
+// withStatus is false when statusDelta() already said what changed
+func drawPrompt(ctx *session, withStatus bool) {
+	defer profileSegment("prompt")()
+	r := ctx.repo()
+	last := ctx.lastCommand(r)
 	cwd, err := os.Getwd()
-	checkErr(err)
+	if err != nil {
+		// e.g. the directory we were in got deleted
+		fmt.Print(last, Red, "(", err, ")", Reset, " % ")
+		return
+	}
 	cwd = normalizePathSeparators(cwd)
 
-	gwd, err := gitDir()
-	if err != nil {
+	if r.err != nil {
 		// not a git repository
-		fmt.Print(Red, "(not a git repository)", Reset, " ", path.Base(cwd), " % ")
+		fmt.Print(last, Red, "(not a git repository)", Reset, " ", path.Base(cwd), " % ")
 		return
 	}
-	gwd = normalizePathSeparators(gwd)
+	gwd := normalizePathSeparators(r.gwd)
 
 	repo := path.Base(gwd)
 
@@ -328,520 +445,155 @@ func prompt() {
 	cwd = strings.TrimPrefix(cwd, gwd)
 
 	// always show working tree status first
-	status()
+	if withStatus {
+		fmt.Print(r.status)
+	}
+	ctx.changeslast, ctx.fileslast = r.changes(), r.files
 
-	fmt.Print(Grey, "git@", Reset, Yellow, head(), Reset, " ", Cyan, repo, cwd, Reset, " % ")
+	fmt.Print(last, Grey, "git@", Reset, Yellow, r.head, Reset, " ", Cyan, repo, cwd, Reset, " % ")
 }
 
-func summary() {
-	lastCommit, _, err := git("log", "-1", "--pretty=%h %an: %s %cr").Output()
-	if err != nil {
-		return
+// exit status if the last command failed, how long it took if that was a while
+func (ctx *session) lastCommand(r *repoState) string {
+	last := ""
+	if status := atomic.LoadInt32(&lastStatus); status != 0 {
+		last += BgRed + " " + strconv.Itoa(int(status)) + " " + Reset + " "
 	}
-	commits, _, err := git("rev-list", "HEAD").Output()
-	checkErr(err)
-	branches, _, err := git("branch").Output()
-	checkErr(err)
-	authors, _, err := git("shortlog", "-s").Output()
-	checkErr(err)
-
-	tw := newTabwriter(os.Stdout)
-	fmt.Fprint(tw, lastCommit)
-	fmt.Fprintf(tw,
-		"commits: %d branches: %d contributors: %d\n",
-		strings.Count(commits, "\n"),
-		strings.Count(branches, "\n"),
-		strings.Count(authors, "\n"),
-	)
-	hasL, _, err := newCmd("which", "l").Output()
-	if hasL != "" && err == nil {
-		l := []struct {
-			Language string
-			Percent  float64
-		}{}
-		data, _, err := newCmd("l", "-json", "-limit", "3").Output()
-		checkErr(err)
-		checkErr(json.Unmarshal([]byte(data), &l))
-		for _, l := range l {
-			fmt.Fprintf(tw, "%s: %.2f%% ", l.Language, l.Percent)
-		}
+	if slow := r.slow; ctx.lastDuration >= slow && slow > 0 {
+		last += Grey + ctx.lastDuration.Round(100*time.Millisecond).String() + Reset + " "
 	}
-	tw.Flush()
+	return last
 }
 
 func main() {
-	// for great justice
+	if len(os.Args) > 1 || !isTerminal(os.Stdin) {
+		os.Exit(runNonInteractive(os.Args[1:]))
+	}
+
+	input = newEventLoop(os.Stdin)
 	zig := make(chan os.Signal, 1)
 	signal.Notify(zig, os.Interrupt)
-	go func() { <-zig; fmt.Println(); os.Exit(0) }()
+	go func() {
+		for sig := range zig {
+			input.post(signalEvent{sig, time.Now()})
+		}
+	}()
 
-	difflast := ""
-	stdout, _, err := git("diff", "--numstat").Output()
-	if err == nil {
-		difflast = strings.TrimSpace(stdout)
-	}
+	ctx := &session{hookslast: hooksNotice()}
 
-	displayUpdate := true
-	statusUpdate := func() {
-		if !displayUpdate {
-			return
-		}
-		stdout, _, err := git("diff", "--numstat").Output()
-		diff := strings.TrimSpace(stdout)
-		if err != nil || diff == difflast {
-			return
+	statusUpdate := func() error {
+		noticed := ctx.hooksUpdate()
+		r := ctx.repo()
+		if r.err != nil || r.changes() == ctx.changeslast {
+			if noticed {
+				// the prompt is above the notice now
+				drawPrompt(ctx, false)
+			}
+			return nil
 		}
-		difflast = diff
 		fmt.Println()
-		prompt()
+		for _, ln := range statusDelta(ctx.fileslast, r.files) {
+			fmt.Println(ln)
+		}
+		drawPrompt(ctx, r.reprint)
 		// 	log.Println(BgMagenta + "[status update here]" + Reset)
+		return nil
 	}
 
-	watchChan := make(chan struct{})
-	inputChan := make(chan struct{})
-	watch, err := newWatcher(
+	mode, _ := config("tiger.watch")
+	var err error
+	ctx.watch, err = newWatcher(mode,
 		func(filename string) bool {
+			if strings.Contains(filename, "/.git/") {
+				return isGitState(filename)
+			}
 			if path.Base(filename) == ".git" {
 				return false
 			}
-
-			// TODO(tso): check if file is ignored before returning true
-			return true
-		},
-		func() {
-			watchChan <- struct{}{}
+			return !ctx.watch.Ignored(filename)
 		},
+		input.change,
 	)
-	checkErr(err)
+	if err != nil {
+		println("", "", fmt.Errorf("not watching for changes: %v", err))
+	} else {
+		go func() {
+			for err := range ctx.watch.errors {
+				input.post(watchErrEvent{err})
+			}
+		}()
+	}
 
-	lastCwd, err := os.Getwd()
-	checkErr(err)
+	ctx.lastCwd, _ = os.Getwd()
 
-	gwd, err := gitDir()
+	ctx.gwd, err = gitDir()
 	if err == nil {
-		go watch.AddWithSubdirs(gwd)
+		go ctx.watch.Watch(ctx.gwd)
 	}
 	// this is where you would put an annoying welcome message
 	// TODO(tso): annoying welcome message
-	prompt()
+	if ctx.hookslast != "" {
+		fmt.Println(ctx.hookslast)
+	}
+	println("", "", ctx.runRC())
+	if notice := aliasNotice(); notice != "" {
+		fmt.Println(notice)
+	}
+	prompt(ctx)
 
-	scanner := bufio.NewScanner(os.Stdin)
-	go func() {
-		for scanner.Scan() {
-			inputChan <- struct{}{}
-			<-inputChan
-		}
-	}()
-	sendInputSignal := false
-everywhere:
+	// everything happens here, one thing at a time. whatever comes in
+	// while a command runs waits for it to finish, changes pile up into
+	// one and by then the prompt is fresh anyway
+	var done time.Time
 	for {
-		if sendInputSignal {
-			inputChan <- struct{}{}
-		}
-		displayUpdate = true
-		select {
-		case <-watchChan:
-			statusUpdate()
-			sendInputSignal = false
-			goto everywhere
-		case <-inputChan:
-			displayUpdate = false
-			sendInputSignal = true
-		}
-		args := splitArgs(scanner.Text())
-
-		// typing "git <command>" out of habit
-		if args[0] == "git" {
-			args = args[1:]
-		}
-
-		if len(args) == 0 {
-			goto there
-		}
-
-	somewhere:
-		switch strings.TrimSpace(args[0]) {
-		case "": // do nothing
-		case "exit", "quit":
-			break everywhere
-
-		case "summary": // github-style summary
-			summary()
-
-		// reinventing coreutils poorly
-		case "cd":
-			if len(args) > 1 {
-				cd := strings.Join(args[1:], " ")
-				if cd == "-" {
-					cd = lastCwd
-				}
-				lastCwd, err = os.Getwd()
-				checkErr(err)
-				err := os.Chdir(cd)
-				if err != nil {
-					fmt.Println(Red, err, Reset)
-				} else {
-					difflast = ""
-					stdout, _, err := git("diff", "--numstat").Output()
-					if err == nil {
-						difflast = strings.TrimSpace(stdout)
-					}
-					currentGwd, err := gitDir()
-					if currentGwd != gwd {
-						watch.RemoveAll()
-						if err == nil {
-							gwd = currentGwd
-							go watch.AddWithSubdirs(gwd)
-						}
-					}
-				}
-			}
-		case "cat":
-			// TODO(tso): this could use a lot of improvements
-			// - resolve relative filepaths
-			// - make it clear somehow that this is not real cat
-
-			if len(args) < 2 {
-				fmt.Println(Red+"usage:"+Reset, "cat [branch (optional)] [filename]")
-				break
-			}
-
-			var treeish, filename string
-			if len(args) >= 3 {
-				treeish = args[1]
-				filename = strings.Join(args[2:], " ")
-			} else {
-				treeish = head()
-				filename = strings.Join(args[1:], " ")
-			}
-
-			stdout, _, err := git("ls-tree", treeish).Output()
-			if err == nil {
-				// we're in a git repository
-				gitFiles := strings.Split(strings.TrimSpace(stdout), "\n")
-				for _, ln := range gitFiles {
-					var (
-						mode              int
-						thing, hash, name string
-					)
-					fmt.Sscanf(ln, "%d %s %s    %s", &mode, &thing, &hash, &name)
-
-					if filename == name {
-						git("cat-file", thing, hash).AttachWithPipe(pager())
-						break somewhere
-					}
-				}
-				fmt.Println(Red+"file:"+Reset, filename, Red+"not found @ revision:"+Reset, treeish)
-			} else {
-				if !fileExists(filename) {
-					fmt.Println(Red+"file not found:"+Reset, filename)
-					break
+		switch e := input.next().(type) {
+		case lineEvent:
+			if e.eof {
+				if e.err != nil {
+					fmt.Println("error reading stdin:", e.err)
 				}
-				newCmd("cat", filename).AttachWithPipe(pager())
+				fmt.Println()
+				return
 			}
-		case "ls":
-			// TODO(tso): this could use a lot of improvements
-			// - columns?
-			// - sorting
-			// - list directories first
-			// - merge the two lists and use colors or a [x] to show
-			//   which files are in the index, which are untracked, ignored...
-			// - diff stats
-			stdout, _, err := git("ls-tree", head()).Output()
-			if err == nil {
-				// we're in a git repository
-				gitFiles := strings.Split(strings.TrimSpace(stdout), "\n")
-				for i, ln := range gitFiles {
-					var (
-						mode              int
-						thing, hash, name string
-					)
-					fmt.Sscanf(ln, "%d %s %s    %s", &mode, &thing, &hash, &name)
-					if thing == "tree" {
-						name += "/"
-					} else if thing != "blob" {
-						name = "(" + thing + ") " + name
-					}
-					gitFiles[i] = name
-				}
-				fmt.Println("files known to git:")
-				for _, f := range gitFiles {
-					fmt.Print("\t", f, "\n")
-				}
+			start := time.Now()
+			if runLine(ctx, e.line, terminal) == errExit {
 				fmt.Println()
+				return
 			}
-
-			cwd, err := os.Open(".")
-			checkErr(err)
-			files, err := cwd.Readdir(-1)
-			checkErr(err)
-
-			fmt.Println("current directory contents:")
-			for _, f := range files {
-				name := f.Name()
-				if f.IsDir() {
-					name += "/"
-				}
-				fmt.Print("\t", name, "\n")
+			if strings.TrimSpace(e.line) != "" {
+				ctx.lastDuration = time.Since(start)
 			}
-
+			println("", "", safely(func() error { prompt(ctx); return nil }))
+			done = time.Now()
+		case changeEvent:
+			println("", "", safely(statusUpdate))
+		case watchErrEvent:
 			fmt.Println()
-
-		case "mkdir": // doesn't do bash shell expansion e.g. mkdir -p go/{bin,pkg,src}/
-			if len(args) != 2 {
-				println("", "", fmt.Errorf("mkdir takes exactly 1 argument"))
-				break
-			}
-			checkErr(os.MkdirAll(args[1], os.ModePerm))
-
-			// "improved" git rm
-			//  - don't fail just because .* matches . and ..
-			//  - don't choke just because [glob pattern] matches untracked or ignored files
-			// accomplishing this for now by globbing first
-			// and doing each rm one at a time
-			// so if one or more operations fail the other operations don't fail
-		case "rm":
-			flags := []string{}
-			paths := []string{}
-			for _, arg := range args[1:] {
-				switch arg {
-				case "-h", "--help", "-f", "--force", "-n", "-r", "--cached", "--ignore-unmatch", "--quiet", "--":
-					flags = append(flags, arg)
-				default:
-					glob, err := filepath.Glob(arg)
-					if err != nil {
-						fmt.Println(err)
-						break somewhere
-					}
-					paths = append(paths, glob...)
-				}
-			}
-			if len(paths) == 0 {
-				git(append([]string{"rm"}, flags...)...).Attach()
-			}
-			for _, path := range paths {
-				git(append([]string{"rm"}, append(flags, path)...)...).Attach()
-			}
-
-		// feature: naked "git config" pretty-prints git config --list
-		case "config":
-			if len(args) != 1 {
-				git(args...).Attach()
-				break
-			}
-			system, _, _ := git("config", "--list", "--system").Output()
-			global, _, _ := git("config", "--list", "--global").Output()
-			local, _, _ := git("config", "--list", "--local").Output()
-			out := &buf{}
-
-			align := func(input string) string {
-				lines := strings.Split(input, "\n")
-				max := 0
-				for _, ln := range lines {
-					idx := strings.Index(ln, "=")
-					if idx > max {
-						max = idx
-					}
-				}
-				for i, ln := range lines {
-					idx := strings.Index(ln, "=")
-					if idx < 0 {
-						continue
-					}
-					key := ln[:idx]
-					value := ln[idx+1:]
-					lines[i] = key + strings.Repeat(" ", max-len(key)) + " = " + value
-				}
-				return strings.Join(lines, "\n")
-			}
-
-			fmt.Fprintln(out, "system:")
-			fmt.Fprintln(out, align(system))
-			fmt.Fprintln(out, "global:")
-			fmt.Fprintln(out, align(global))
-			fmt.Fprintln(out, "local:")
-			fmt.Fprintln(out, align(local))
-
-			less := pager()
-			less.Stdin = out
-			less.Stdout = os.Stdout
-			less.Stderr = os.Stderr
-			less.Run()
-
-			// feature: keep: ignore a subdir's contents but keep the dir in the working tree
-		case "keep":
-			if len(args) != 2 {
-				println("", "", fmt.Errorf("keep takes exactly 1 argument"))
-				break
-			}
-
-			// make sure we're in a git repo
-			_, err := gitDir()
-			if err != nil {
-				println("", "", fmt.Errorf("keep only works in a git repo!!"))
-				break
-			}
-
-			dir := strings.TrimRight(args[1], "\\/")
-			if !fileExists(dir) {
-				checkErr(os.MkdirAll(dir, os.ModePerm))
-			}
-			if !isDir(dir) {
-				println("", "", fmt.Errorf("%s is not a directory", dir))
-				break
-			}
-			keepFile := dir + PATH_SEPARATOR + ".keep"
-			if !fileExists(keepFile) {
-				f, err := os.Create(keepFile)
-				checkErr(err)
-				f.Close()
-			}
-
-			f, abspath, err := ignoreFile()
-			checkErr(err)
-			io.WriteString(f, dir+"/\n!"+dir+"/.keep\n")
-			f.Close()
-			git("add", "-f", keepFile, abspath).Attach()
-
-		// feature: ignore/unignore: add lines to .gitignore
-		case "unignore":
-			for i := 1; i < len(args); i++ {
-				args[i] = "!" + args[i]
-			}
-			fallthrough
-		case "ignore":
-			f, abspath, err := ignoreFile()
-			if err != nil {
-				println("", "", err)
-				break
-			}
-			for _, arg := range args[1:] {
-				io.WriteString(f, arg+"\n")
-			}
-			f.Close()
-			git("add", abspath).Attach()
-
-		// feature: draft: edit commit message while staging
-		case "draft":
-			draft, err := draftFile()
-			if err != nil {
-				println("", "", err)
-				break
-			}
-			ed, err := config("core.editor")
-			if err != nil {
-				println("", "", err)
-				break
-			}
-			newCmd(ed, draft).Attach()
-
-		case "commit":
-			draft, err := draftFile()
-			if err == nil {
-				if fileExists(draft) {
-					if len(args) == 1 {
-						msg := fileGetContents(draft)
-						println(git("commit", "-m", msg).Output())
-						os.Remove(draft)
-					} else {
-						ch := make(chan struct{}, 1)
-						go func() { println(git("commit", "-t", draft).Output()); ch <- struct{}{} }()
-						<-time.After(time.Millisecond * 100)
-						os.Remove(draft)
-						<-ch
-					}
-					break
-				}
-			}
-
-			if len(args) == 1 {
-				// standard behavior (open editor, abort due to empty message)
-				git("commit").Attach()
-				break
-			}
-			args = args[1:]
-			flags := []string{}
-		here:
-			for n, arg := range args {
-				switch arg {
-				case "-m": // NOTE(tso): -m eats everything to end-of-line and uses it as commit message!
-					// enhanced behavior: accomodate one-liner commit message
-					//     ∗ always --allow-empty-message
-					flags = append(flags, "--allow-empty-message")
-					msg := ""
-					if len(args) > n+1 {
-						msg = strings.Join(args[n+1:], " ")
-					} else {
-						fmt.Println("enter commit message (optional):")
-						scanner.Scan()
-						msg = scanner.Text()
-					}
-					flags = append(flags, "-m", msg)
-					break here
-				default:
-					flags = append(flags, arg)
-				}
-			}
-			git(append([]string{"commit"}, flags...)...).Attach()
-
-			// feature: checkin: add everything, commit, and push
-		case "ci", "checkin":
-			// TODO(tso): this is a massive hack right now, make it sane
-			stdout, _, err := git("status", "--porcelain").Output()
-			if err != nil {
-				println("", "", err)
-				break
-			}
-
-			modified := false
-			for _, ln := range strings.Split(stdout, "\n") {
-				if len(ln) > 0 && ln[0] != ' ' && ln[0] != '?' {
-					modified = true
-					break
-				}
-			}
-
-			var answer string
-			if !modified {
-				fmt.Println(Cyan + "git add ." + Reset + " first? [if you don't type \"no\" I'm going to do it anyway]")
-				scanner.Scan()
-				answer = scanner.Text()
-				if strings.ToLower(answer) == "no" {
-					fmt.Println("[" + BgRed + " abort " + Reset + "]")
-					break
-				} else {
-					if println(git("add", ".").Output()) == nil {
-						fmt.Println("[ " + Green + "OK" + Reset + " ]")
-					} else {
-						fmt.Println("[" + BgRed + " abort " + Reset + "]")
-						break
-					}
-				}
-			}
-
-			msg := strings.Join(args[1:], " ")
-			if msg == "" {
-				msg = answer
-			}
-			if msg == "" {
-				fmt.Println("enter commit message (optional):")
-				scanner.Scan()
-				msg = scanner.Text()
-			}
-			if println(git("commit", "--allow-empty", "--allow-empty-message", "-m", msg).Output()) == nil {
-				_, _, err := git("remote", "show", "origin").Output()
-				if err == nil {
-					println(git("push").Output())
-				}
+			println("", "", e.err)
+			prompt(ctx)
+		case fetchEvent:
+			fmt.Println()
+			fmt.Println(Yellow + "fetch:" + Reset + " new commits on the remote (see: fetch)")
+			fmt.Println(e.summary)
+			prompt(ctx)
+		case signalEvent:
+			// ^C while a command was running went to the command
+			if e.t.After(done) {
+				bye()
 			}
-		default: // treat all other git commands as usual
-			git(args...).Attach()
 		}
-	there:
-		prompt()
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Println("error reading stdin:", err)
+}
+
+// tell you when .githooks/ changed, true if we did
+func (ctx *session) hooksUpdate() (noticed bool) {
+	defer profileSegment("hooks")()
+	notice := hooksNotice()
+	if notice != ctx.hookslast && notice != "" {
+		fmt.Println()
+		fmt.Println(notice)
+		noticed = true
 	}
-	fmt.Println()
+	ctx.hookslast = notice
+	return noticed
 }
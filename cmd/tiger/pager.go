@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// same order git uses to find a pager, "" means use ours
+//
+// setting any of them to "" turns paging off, just like git
+func pagerCommand() string {
+	p, ok := os.LookupEnv("GIT_PAGER")
+	if !ok {
+		p, ok = configOK("core.pager")
+	}
+	if !ok {
+		p, ok = os.LookupEnv("PAGER")
+	}
+	if ok {
+		if p == "" {
+			return "cat"
+		}
+		return p
+	}
+	if _, err := exec.LookPath("less"); err == nil {
+		return "less -FRX"
+	}
+	return ""
+}
+
+// show output one screen at a time, unless it fits on one screen anyway
+func page(std *stdio, r io.Reader) error {
+	contents := &buf{}
+	if _, err := io.Copy(contents, r); err != nil {
+		return err
+	}
+
+	if std.out != os.Stdout || !isTerminal(os.Stdout) {
+		_, err := io.Copy(std.out, contents)
+		return err
+	}
+
+	rows, _ := terminalSize()
+	lines := strings.Split(strings.TrimSuffix(contents.String(), "\n"), "\n")
+	if len(lines) < rows {
+		_, err := io.Copy(std.out, contents)
+		return err
+	}
+
+	p := pagerCommand()
+	switch p {
+	case "":
+		return (&builtinPager{lines: lines, rows: rows}).run()
+	case "cat":
+		_, err := io.Copy(std.out, contents)
+		return err
+	}
+	// NOTE(tso): core.pager can have any arbitrary shell syntax
+	//            e.g.(mine right now): diff-so-fancy | less -RFX
+	//            rather than try to reinvent bash just to be able to
+	//            create an epic Pipe() abstraction
+	//            let's just do this for now, consequences be damned:
+	// -tso 2018-08-03 00:59:23a
+	less := exec.Command("sh", "-c", p)
+	less.Stdin = contents
+	less.Stdout = std.out
+	less.Stderr = std.err
+	return less.Run()
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+func stty(args ...string) (string, error) {
+	c := exec.Command("stty", args...)
+	c.Stdin = os.Stdin
+	out, err := c.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func terminalSize() (rows, cols int) {
+	rows, cols = 24, 80
+	size, err := stty("size")
+	if err != nil {
+		return
+	}
+	var r, c int
+	fmt.Sscanf(size, "%d %d", &r, &c)
+	if r > 1 && c > 0 { // 0 0 when there's no window e.g. script(1)
+		rows, cols = r, c
+	}
+	return
+}
+
+// for when there's no pager around: less, but less
+//
+//	q               quit
+//	j k ↑ ↓ enter   one line
+//	space b f       one screen
+//	g G             beginning, end
+//	/ n N           search, next, previous
+type builtinPager struct {
+	lines  []string
+	top    int
+	rows   int
+	search string
+}
+
+func (p *builtinPager) run() error {
+	saved, err := stty("-g")
+	if err != nil {
+		return err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return err
+	}
+	defer stty(saved)
+
+	key := make([]byte, 8)
+	for {
+		p.draw()
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return err
+		}
+		screen := p.rows - 1
+		switch string(key[:n]) {
+		case "q", "Q":
+			fmt.Print("\r\033[K")
+			return nil
+		case "j", "\n", "\r", "\033[B", "e":
+			p.scroll(1)
+		case "k", "\033[A", "y":
+			p.scroll(-1)
+		case " ", "f", "\033[6~":
+			p.scroll(screen)
+		case "b", "\033[5~":
+			p.scroll(-screen)
+		case "d":
+			p.scroll(screen / 2)
+		case "u":
+			p.scroll(-screen / 2)
+		case "g", "<":
+			p.top = 0
+		case "G", ">":
+			p.scroll(len(p.lines))
+		case "/":
+			if err := p.readSearch(); err != nil {
+				return err
+			}
+			p.find(1)
+		case "n":
+			p.find(1)
+		case "N":
+			p.find(-1)
+		}
+	}
+}
+
+func (p *builtinPager) scroll(n int) {
+	p.top += n
+	if max := len(p.lines) - (p.rows - 1); p.top > max {
+		p.top = max
+	}
+	if p.top < 0 {
+		p.top = 0
+	}
+}
+
+// jump to the next (dir = 1) or previous (dir = -1) line that matches
+func (p *builtinPager) find(dir int) {
+	if p.search == "" {
+		return
+	}
+	for i := p.top + dir; i >= 0 && i < len(p.lines); i += dir {
+		if strings.Contains(p.lines[i], p.search) {
+			p.top = i
+			p.scroll(0)
+			return
+		}
+	}
+	fmt.Print("\a")
+}
+
+func (p *builtinPager) readSearch() error {
+	fmt.Print("\r\033[K/")
+	search := []byte{}
+	key := make([]byte, 8)
+	for {
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return err
+		}
+		switch k := key[:n]; {
+		case k[0] == '\n' || k[0] == '\r':
+			if len(search) > 0 {
+				p.search = string(search)
+			}
+			return nil
+		case k[0] == '\033':
+			return nil
+		case k[0] == 127 || k[0] == '\b':
+			if len(search) > 0 {
+				search = search[:len(search)-1]
+				fmt.Print("\b \b")
+			}
+		default:
+			search = append(search, k...)
+			fmt.Print(string(k))
+		}
+	}
+}
+
+func (p *builtinPager) draw() {
+	fmt.Print("\033[H\033[2J")
+	end := p.top + p.rows - 1
+	if end > len(p.lines) {
+		end = len(p.lines)
+	}
+	for _, ln := range p.lines[p.top:end] {
+		if p.search != "" {
+			ln = strings.Replace(ln, p.search, BgYellow+Black+p.search+Reset, -1)
+		}
+		fmt.Print(ln, "\r\n")
+	}
+	status := strconv.Itoa(100*end/len(p.lines)) + "%"
+	if end == len(p.lines) {
+		status = "(END)"
+	}
+	fmt.Print(Black+BgGrey, " ", status, " ", Reset)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// only env as the environment for the variables in keys, and no git config
+// other than core.<key> from env
+func testEnv(t *testing.T, keys []string, env map[string]string) {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_COUNT", "0")
+	keys = append(keys, "GIT_CONFIG_KEY_0", "GIT_CONFIG_VALUE_0")
+	for _, k := range keys {
+		t.Setenv(k, "")
+		if v, ok := env[k]; ok {
+			os.Setenv(k, v)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+	for _, k := range []string{"core.pager", "core.editor"} {
+		if v, ok := env[k]; ok {
+			os.Setenv("GIT_CONFIG_COUNT", "1")
+			os.Setenv("GIT_CONFIG_KEY_0", k)
+			os.Setenv("GIT_CONFIG_VALUE_0", v)
+		}
+	}
+}
+
+func TestPagerCommand(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	less := ""
+	if _, err := exec.LookPath("less"); err == nil {
+		less = "less -FRX"
+	}
+	for _, test := range []struct {
+		env      map[string]string
+		expected string
+	}{
+		{map[string]string{}, less},
+		{map[string]string{"PAGER": "more"}, "more"},
+		{map[string]string{"PAGER": "more", "core.pager": "diff-so-fancy | less -RFX"}, "diff-so-fancy | less -RFX"},
+		{map[string]string{"PAGER": "more", "core.pager": "most", "GIT_PAGER": "bat"}, "bat"},
+		// "" means no pager, no matter what comes after it
+		{map[string]string{"GIT_PAGER": "", "core.pager": "most"}, "cat"},
+		{map[string]string{"core.pager": "", "PAGER": "more"}, "cat"},
+		{map[string]string{"PAGER": ""}, "cat"},
+	} {
+		testEnv(t, []string{"GIT_PAGER", "PAGER"}, test.env)
+		if actual := pagerCommand(); actual != test.expected {
+			t.Fatalf("%v: expected %q, got %q", test.env, test.expected, actual)
+		}
+	}
+}
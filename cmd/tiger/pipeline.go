@@ -0,0 +1,198 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// run everything typed at the prompt
+//
+// errors are printed as they happen so you can see which part of
+// `a && b; c` went wrong, what's returned is the status of the last pipeline
+//
+// params are $0 $1 ... when running a macro
+func runLine(ctx *session, line string, std *stdio, params ...string) error {
+	if atomic.LoadInt32(&ctx.depth) == 0 {
+		forgetConfig()
+		// whatever ran probably changed something and the watcher may not
+		// have heard about it yet
+		if strings.TrimSpace(line) != "" {
+			defer func() { ctx.state = nil }()
+		}
+	}
+	// the whole line is for the shell, including any | && ;
+	if line = strings.TrimSpace(line); strings.HasPrefix(line, "!") {
+		err := ctx.shell(std, line[1:])
+		setStatus(err)
+		return err
+	}
+
+	pipelines, ops, err := splitLine(line)
+	if err != nil {
+		setStatus(err)
+		return println("", "", err)
+	}
+
+	err = nil
+	for i, src := range pipelines {
+		if i > 0 {
+			switch ops[i-1] {
+			case "&&":
+				if err != nil {
+					continue
+				}
+			case "||":
+				if err == nil {
+					continue
+				}
+			}
+		}
+		list, perr := parseLine(src, params...)
+		switch {
+		case perr != nil: // $EMPTY > file
+			err = perr
+		case len(list.pipelines) == 0: // $EMPTY
+			err = nil
+		default:
+			err = safely(func() error { return runPipeline(ctx, list.pipelines[0], std) })
+		}
+		setStatus(err)
+		if err == errExit {
+			return err
+		}
+		if err != nil && !isExitErr(err) {
+			println("", "", err)
+		}
+	}
+	return err
+}
+
+// $? is the exit status of the last command
+var lastStatus int32
+
+func setStatus(err error) {
+	atomic.StoreInt32(&lastStatus, int32(exitStatus(err)))
+}
+
+func runPipeline(ctx *session, p *pipeline, std *stdio) error {
+	if len(p.cmds) == 1 {
+		cio, closeAll, err := redirectIO(p.cmds[0].redirs, std)
+		if err != nil {
+			return err
+		}
+		defer closeAll()
+		return dispatch(ctx, p.cmds[0].args, cio)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(p.cmds))
+		in   io.Reader
+	)
+	in = std.in
+	for i, c := range p.cmds {
+		stage := &stdio{in, std.out, std.err}
+		var w *io.PipeWriter
+		if i < len(p.cmds)-1 {
+			var r *io.PipeReader
+			r, w = io.Pipe()
+			stage.out = w
+			in = r
+		}
+
+		// the pipe from the previous stage, closed when we're done with it
+		up, _ := stage.in.(*io.PipeReader)
+		cio, closeAll, err := redirectIO(c.redirs, stage)
+		if err != nil {
+			errs[i] = err
+			if w != nil {
+				w.Close()
+			}
+			if up != nil {
+				up.Close()
+			}
+			continue
+		}
+		// < file, nobody's going to read the pipe
+		if up != nil && cio.in != stage.in {
+			up.Close()
+		}
+
+		wg.Add(1)
+		go func(i int, c *simpleCmd, cio *stdio, w *io.PipeWriter, up *io.PipeReader) {
+			defer wg.Done()
+			errs[i] = safely(func() error {
+				if i > 0 {
+					return dispatchPiped(ctx, c.args, cio)
+				}
+				return dispatch(ctx, c.args, cio)
+			})
+			// only the last one is returned, but you still want to know
+			if errs[i] != nil && !isExitErr(errs[i]) && i < len(errs)-1 {
+				println("", "", errs[i])
+			}
+			closeAll()
+			if w != nil {
+				w.Close()
+			}
+			// nobody's reading anymore, don't leave whoever's writing hanging
+			if up != nil {
+				up.Close()
+			}
+		}(i, c, cio, w, up)
+	}
+	wg.Wait()
+
+	return errs[len(errs)-1]
+}
+
+// git commands don't read from stdin, so after a | it's more likely you meant
+// a program like grep or less. type "git grep" if you really meant git grep
+func dispatchPiped(ctx *session, args []string, std *stdio) error {
+	if len(args) > 0 && args[0] != "git" {
+		if _, ok := lookup(args[0]); !ok {
+			if _, err := exec.LookPath(args[0]); err == nil {
+				return newCmd(args[0], args[1:]...).AttachIO(std)
+			}
+		}
+	}
+	return dispatch(ctx, args, std)
+}
+
+// open files for < > >>
+func redirectIO(redirs []redirect, std *stdio) (*stdio, func(), error) {
+	cio := &stdio{std.in, std.out, std.err}
+	files := []*os.File{}
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, r := range redirs {
+		var (
+			f   *os.File
+			err error
+		)
+		switch r.op {
+		case "<":
+			f, err = os.Open(r.file)
+			cio.in = f
+		case ">":
+			f, err = os.Create(r.file)
+			cio.out = f
+		case ">>":
+			f, err = os.OpenFile(r.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			cio.out = f
+		}
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, f)
+	}
+	return cio, closeAll, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// polling looks at every file in the repository once in a while and makes up
+// the events inotify would have sent. how often depends on how long that
+// takes: a big repository gets looked at less often
+const (
+	minPollInterval = time.Second
+	maxPollInterval = 30 * time.Second
+)
+
+type fileStamp struct {
+	mtime, size int64
+}
+
+func init() {
+	register(&builtin{
+		name:  "watch",
+		usage: "watch",
+		about: "how tiger is watching for changes, see tiger.watch in git config",
+		run: func(ctx *session, args []string, io *stdio) error {
+			w := ctx.watch
+			if w == nil {
+				fmt.Fprintln(io.out, "not watching for changes")
+				return nil
+			}
+			w.mu.Lock()
+			mode, reason, dirs := w.mode, w.reason, len(w.paths)
+			w.mu.Unlock()
+			if mode == "poll" {
+				interval := time.Duration(atomic.LoadInt64(&w.interval))
+				fmt.Fprintf(io.out, "polling for changes every %s (%s)\n", interval.Round(time.Millisecond), reason)
+				return nil
+			}
+			fmt.Fprintf(io.out, "watching %d directories for changes\n", dirs)
+			return nil
+		},
+	})
+}
+
+func (w *watcher) poll() {
+	var (
+		root string
+		prev map[string]fileStamp
+	)
+	for {
+		w.mu.Lock()
+		current := w.root
+		w.mu.Unlock()
+
+		start := time.Now()
+		files := w.scan(current)
+		took := time.Since(start)
+
+		// nothing to compare to after a cd
+		if current == root && prev != nil {
+			for _, name := range changedFiles(prev, files) {
+				w.events <- &event{filename: name, t: time.Now()}
+			}
+		}
+		root, prev = current, files
+
+		// don't spend more than a tenth of the time looking
+		interval := 10 * took
+		if interval < minPollInterval {
+			interval = minPollInterval
+		}
+		if interval > maxPollInterval {
+			interval = maxPollInterval
+		}
+		atomic.StoreInt64(&w.interval, int64(interval))
+		time.Sleep(interval)
+	}
+}
+
+// the same files the watcher would: everything in root that isn't ignored,
+// and in .git only what the prompt shows
+func (w *watcher) scan(root string) map[string]fileStamp {
+	files := map[string]fileStamp{}
+	if root == "" {
+		return files
+	}
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // deleted while we were looking, that's fine
+		}
+		path = normalizePathSeparators(path)
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				for _, name := range []string{"HEAD", "index", "packed-refs"} {
+					if info, err := os.Stat(filepath.Join(path, name)); err == nil {
+						files[path+"/"+name] = fileStamp{info.ModTime().UnixNano(), info.Size()}
+					}
+				}
+				filepath.Walk(filepath.Join(path, "refs"), func(path string, info os.FileInfo, err error) error {
+					if err == nil && !info.IsDir() {
+						files[normalizePathSeparators(path)] = fileStamp{info.ModTime().UnixNano(), info.Size()}
+					}
+					return nil
+				})
+				return filepath.SkipDir
+			}
+			if path != root && w.Ignored(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files[path] = fileStamp{info.ModTime().UnixNano(), info.Size()}
+		return nil
+	})
+	return files
+}
+
+// created, deleted or modified
+func changedFiles(before, after map[string]fileStamp) []string {
+	changed := []string{}
+	for name, stamp := range after {
+		if prev, ok := before[name]; !ok || prev != stamp {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(&builtin{
+		name:     "push",
+		usage:    "push [git push args]",
+		about:    "sets up the upstream branch and pulls --rebase when needed",
+		complete: completeRemotes,
+		run: func(ctx *session, args []string, io *stdio) error {
+			return push(io, args[1:])
+		},
+	})
+}
+
+// push is git push except:
+//   - when the current branch has no upstream, offer to push -u <remote> <branch>
+//   - when the remote has commits we don't, offer to pull --rebase and try again
+//   - when remotes aren't set up, offer to set them up and try again
+func push(io *stdio, args []string) error {
+	orig := args
+	pull := []string{"pull", "--rebase"}
+
+	if len(args) == 0 && !hasUpstream() {
+		branch, err := currentBranch()
+		remote := chooseRemote()
+		if err == nil && remote != "" {
+			if !confirm(fmt.Sprintf("%s has no upstream branch. push -u %s %s?", branch, remote, branch)) {
+				return fmt.Errorf("push aborted")
+			}
+			args = []string{"-u", remote, branch}
+			pull = append(pull, remote, branch)
+		}
+	}
+
+	pushArgs := append([]string{"push"}, args...)
+	stderr, err := gitCapture(io, pushArgs...)
+	if err != nil && remoteProblem(stderr) {
+		if !confirm("looks like your remotes aren't set up. do that now?") {
+			return err
+		}
+		if err := remoteWizard(); err != nil {
+			return err
+		}
+		return push(io, orig)
+	}
+	if err == nil || !pushRejected(stderr) {
+		return err
+	}
+	if !confirm("the remote has commits that you don't have. pull --rebase and push again?") {
+		return err
+	}
+	if err := remoteCmd(io, pull); err != nil {
+		return err
+	}
+	return git(pushArgs...).AttachIO(io)
+}
+
+// push was rejected because we're behind the remote
+func pushRejected(stderr string) bool {
+	return strings.Contains(stderr, "[rejected]") &&
+		(strings.Contains(stderr, "(fetch first)") || strings.Contains(stderr, "(non-fast-forward)"))
+}
+
+// origin if there's only origin, otherwise ask
+func chooseRemote() string {
+	stdout, _, err := git("remote").Output()
+	if err != nil {
+		return ""
+	}
+	remotes := strings.Fields(stdout)
+	switch len(remotes) {
+	case 0:
+		return ""
+	case 1:
+		return remotes[0]
+	}
+
+	def := remotes[0]
+	if contains(remotes, "origin") {
+		def = "origin"
+	}
+	for i, r := range remotes {
+		fmt.Printf("%d) %s\n", i+1, r)
+	}
+	fmt.Printf("which remote? [%s] ", def)
+	answer := strings.TrimSpace(readLine())
+	if n, err := strconv.Atoi(answer); err == nil && n > 0 && n <= len(remotes) {
+		return remotes[n-1]
+	}
+	if contains(remotes, answer) {
+		return answer
+	}
+	return def
+}
+
+// git only shows progress and colours on a terminal and we're in the
+// middle of its stderr to find out what went wrong, so ask for them
+func gitCapture(std *stdio, args ...string) (stderr string, err error) {
+	if f, ok := std.err.(*os.File); ok && isTerminal(f) && len(args) > 0 {
+		args = append([]string{
+			"-c", "color.remote=always", "-c", "color.advice=always",
+			args[0], "--progress",
+		}, args[1:]...)
+	}
+	return git(args...).AttachCapture(std)
+}
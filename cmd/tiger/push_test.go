@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPushRejected(t *testing.T) {
+	for stderr, expected := range map[string]bool{
+		" ! [rejected]        main -> main (fetch first)\nerror: failed to push some refs":      true,
+		" ! [rejected]        main -> main (non-fast-forward)\nerror: failed to push some refs": true,
+		" ! [rejected]        v1 -> v1 (already exists)":                                        false,
+		" ! [remote rejected] main -> main (pre-receive hook declined)":                         false,
+		"fatal: 'origin' does not appear to be a git repository":                                false,
+		"": false,
+	} {
+		if pushRejected(stderr) != expected {
+			t.Fatalf("pushRejected(%q) should be %v", stderr, expected)
+		}
+	}
+}
+
+func TestRemoteProblem(t *testing.T) {
+	for stderr, expected := range map[string]bool{
+		"fatal: No configured push destination.":                                  true,
+		"fatal: No remote repository specified.  Please, specify either a URL or": true,
+		"fatal: 'origin' does not appear to be a git repository":                  true,
+		"fatal: Could not read from remote repository.":                           true,
+		"error: No such remote 'upstream'":                                        true,
+		" ! [rejected]        main -> main (fetch first)":                         false,
+		"fatal: Authentication failed for 'https://example.com/x.git/'":           false,
+		"": false,
+	} {
+		if remoteProblem(stderr) != expected {
+			t.Fatalf("remoteProblem(%q) should be %v", stderr, expected)
+		}
+	}
+}
+
+// run git in dir, or fail the test
+func gitIn(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// push against a local bare remote: no upstream yet, then rejected
+// because someone else pushed first
+func TestPush(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, kv := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(kv, "tiger@example.com")
+	}
+	dir := t.TempDir()
+	remote, a, b := dir+"/remote.git", dir+"/a", dir+"/b"
+	gitIn(t, dir, "init", "-q", "--bare", "-b", "main", remote)
+	gitIn(t, dir, "init", "-q", "-b", "main", a)
+	gitIn(t, a, "remote", "add", "origin", remote)
+	gitIn(t, a, "commit", "-q", "--allow-empty", "-m", "first")
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(a); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { input = nil }()
+	out, errs := &buf{}, &buf{}
+	std := &stdio{&buf{}, out, errs}
+
+	// main has no upstream branch. push -u origin main?
+	input = newEventLoop(strings.NewReader("y\n"))
+	if err := push(std, nil); err != nil {
+		t.Fatalf("%v\n%s%s", err, out, errs)
+	}
+	if gitIn(t, a, "rev-parse", "--abbrev-ref", "@{u}") != "origin/main" {
+		t.Fatal("push -u didn't set the upstream branch")
+	}
+
+	gitIn(t, dir, "clone", "-q", remote, b)
+	gitIn(t, b, "commit", "-q", "--allow-empty", "-m", "theirs")
+	gitIn(t, b, "push", "-q")
+	gitIn(t, a, "commit", "-q", "--allow-empty", "-m", "ours")
+
+	// the remote has commits that you don't have. pull --rebase and push again?
+	input = newEventLoop(strings.NewReader("y\n"))
+	if err := push(std, nil); err != nil {
+		t.Fatalf("%v\n%s%s", err, out, errs)
+	}
+	if log := gitIn(t, remote, "log", "--format=%s", "main"); log != "ours\ntheirs\nfirst" {
+		t.Fatalf("expected ours on top of theirs on the remote, got:\n%s", log)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// things git says when remotes aren't set up (correctly)
+var remoteErrors = []string{
+	"No configured push destination",
+	"No remote repository specified",
+	"does not appear to be a git repository",
+	"Could not read from remote repository",
+	"No such remote",
+}
+
+func remoteProblem(stderr string) bool {
+	for _, e := range remoteErrors {
+		if strings.Contains(stderr, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	for _, name := range []string{"pull", "fetch"} {
+		register(&builtin{
+			name:     name,
+			usage:    name + " [git " + name + " args]",
+			about:    "offers to set up remotes when there aren't any",
+			complete: completeRemotes,
+			run: func(ctx *session, args []string, io *stdio) error {
+				return remoteCmd(io, args)
+			},
+		})
+	}
+}
+
+// run a git command that talks to a remote (push, pull, fetch)
+// and offer to setup remotes if that's why it failed
+func remoteCmd(io *stdio, args []string) error {
+	stderr, err := gitCapture(io, args...)
+	if err == nil || !remoteProblem(stderr) {
+		return err
+	}
+	if !confirm("looks like your remotes aren't set up. do that now?") {
+		return err
+	}
+	if err := remoteWizard(); err != nil {
+		return err
+	}
+	return git(args...).AttachIO(io)
+}
+
+// remote, then branch
+func completeRemotes(args []string) []string {
+	if len(args) > 1 {
+		return completeRefs(lastArg(args))
+	}
+	stdout, _, err := git("remote").Output()
+	if err != nil {
+		return nil
+	}
+	return completeWords(lastArg(args), strings.Fields(stdout))
+}
+
+func remoteWizard() error {
+	for {
+		stdout, _, err := git("remote", "-v").Output()
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		if strings.TrimSpace(stdout) == "" {
+			fmt.Println("no remotes.")
+		} else {
+			fmt.Print(stdout)
+		}
+		fmt.Print(Cyan, "[a]dd [r]ename [u]rl [d]one", Reset, " ")
+
+		switch strings.ToLower(strings.TrimSpace(readLine())) {
+		case "a", "add":
+			name := ask("name", "origin")
+			url := ask("url", "")
+			if url == "" {
+				continue
+			}
+			if println(git("remote", "add", name, url).Output()) != nil {
+				continue
+			}
+			if pushURL := ask("push url (if it's different)", url); pushURL != url {
+				println(git("remote", "set-url", "--push", name, pushURL).Output())
+			}
+		case "r", "rename":
+			remote := chooseRemote()
+			if remote == "" {
+				continue
+			}
+			if name := ask("new name", remote); name != remote {
+				println(git("remote", "rename", remote, name).Output())
+			}
+		case "u", "url", "set-url":
+			remote := chooseRemote()
+			if remote == "" {
+				continue
+			}
+			fetchURL, _ := config("remote." + remote + ".url")
+			if url := ask("fetch url", fetchURL); url != fetchURL {
+				println(git("remote", "set-url", remote, url).Output())
+				fetchURL = url
+			}
+			pushURL, err := config("remote." + remote + ".pushurl")
+			if err != nil {
+				pushURL = fetchURL
+			}
+			if url := ask("push url", pushURL); url != pushURL {
+				println(git("remote", "set-url", "--push", remote, url).Output())
+			}
+		case "", "d", "done", "q", "quit":
+			return nil
+		}
+	}
+}
+
+// ask for something, with a default for when you just hit enter
+func ask(question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer := strings.TrimSpace(readLine())
+	if answer == "" {
+		return def
+	}
+	return answer
+}
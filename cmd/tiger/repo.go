@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// what the prompt shows about the repository, kept between prompts so
+// redrawing it doesn't ask git the same questions all over again
+//
+// it's thrown away after every command, when the working directory changes,
+// when the watcher sees something change in the working tree, or when
+// .git/HEAD, index, packed-refs, config or the current branch's ref have been
+// modified.
+// without a watcher, or one that polls, there's no telling when the
+// working tree changed, so nothing is kept
+type repoState struct {
+	stamp   repoStamp
+	gwd     string
+	err     error             // not a git repository
+	head    string            // head()
+	commit  string            // what HEAD points to
+	status  string            // status()
+	files   map[string]string // status()
+	numstat string            // git diff --numstat
+	slow    time.Duration     // tiger.slowCommand
+	reprint bool              // tiger.reprintStatus
+}
+
+type repoStamp struct {
+	cwd        string
+	generation int64
+	mtimes     [5]int64
+}
+
+func newRepoStamp(cwd, gwd string, w *watcher) repoStamp {
+	s := repoStamp{cwd: cwd, generation: w.Generation()}
+	dir := filepath.Join(gwd, ".git")
+	files := []string{"HEAD", "index", "packed-refs", "config"}
+	if ref, err := fileGetContents(filepath.Join(dir, "HEAD")); err == nil && strings.HasPrefix(ref, "ref: ") {
+		files = append(files, strings.TrimSpace(strings.TrimPrefix(ref, "ref: ")))
+	}
+	for i, name := range files {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			s.mtimes[i] = info.ModTime().UnixNano()
+		}
+	}
+	return s
+}
+
+func (s repoStamp) valid() bool {
+	return s.generation >= 0
+}
+
+// the repository as of now, only asks git if something changed
+func (ctx *session) repo() *repoState {
+	cwd, _ := os.Getwd()
+	if r := ctx.state; r != nil && r.err == nil && r.stamp.valid() {
+		if r.stamp == newRepoStamp(cwd, r.gwd, ctx.watch) {
+			return r
+		}
+	}
+
+	r := &repoState{slow: 2 * time.Second, reprint: true}
+	done := profileSegment("repo")
+	r.gwd, r.err = gitDir()
+	done()
+	// before asking git anything, so that whatever changes in the meantime
+	// shows up next time
+	r.stamp = newRepoStamp(cwd, r.gwd, ctx.watch)
+
+	if d, err := config("tiger.slowCommand"); err == nil && d != "" {
+		if d, err := time.ParseDuration(d); err == nil {
+			r.slow = d
+		}
+	}
+	if v, err := config("tiger.reprintStatus"); err == nil && v == "false" {
+		r.reprint = false
+	}
+	if r.err == nil {
+		stdout, _, err := git("diff", "--numstat").Output()
+		if err == nil {
+			r.numstat = strings.TrimSpace(stdout)
+		}
+		stdout, _, err = git("rev-parse", "-q", "--verify", "HEAD").Output()
+		if err == nil {
+			r.commit = strings.TrimSpace(stdout)
+		}
+		r.head = headIn(r.gwd)
+		r.status, r.files = status(r.numstat)
+	}
+	ctx.state = r
+	return r
+}
+
+// if any of these changed the prompt is worth redrawing without being asked,
+// including new untracked files (ignored ones never get this far)
+func (r *repoState) changes() string {
+	files := []string{}
+	for _, line := range r.files {
+		files = append(files, line)
+	}
+	sort.Strings(files)
+	return strings.Join(append([]string{r.head, r.commit, r.numstat}, files...), "\x00")
+}
+
+// bumped for every change in the working tree, -1 without a watcher or
+// while polling, which can take up to maxPollInterval to notice anything
+func (w *watcher) Generation() int64 {
+	if w == nil {
+		return -1
+	}
+	w.mu.Lock()
+	polling := w.w == nil
+	w.mu.Unlock()
+	if polling {
+		return -1
+	}
+	return atomic.LoadInt64(&w.generation)
+}
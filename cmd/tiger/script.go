@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// false when there's nobody to answer questions
+var interactive = true
+
+// for scripts and makefiles:
+//
+//	tiger -c 'command line' [$0 [$1 ...]]
+//	tiger script.tiger [$1 ...]
+//	tiger < script.tiger
+//
+// no prompt, no status, no ~/.tigerrc. questions get answered with no and
+// the exit status is the last command's
+func runNonInteractive(args []string) int {
+	interactive = false
+	ctx := &session{}
+
+	var err error
+	switch {
+	case len(args) > 0 && args[0] == "-c":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "usage: tiger -c 'command line' [args...]")
+			return 2
+		}
+		params := args[2:]
+		if len(params) == 0 {
+			params = []string{"tiger"}
+		}
+		err = runLine(ctx, args[1], terminal, params...)
+	case len(args) > 0 && strings.HasPrefix(args[0], "-"):
+		fmt.Fprintln(os.Stderr, "usage: tiger [-c 'command line' | script] [args...]")
+		return 2
+	case len(args) > 0:
+		f, ferr := os.Open(args[0])
+		if ferr != nil {
+			println("", "", ferr)
+			return 1
+		}
+		defer f.Close()
+		err = runScript(ctx, f, terminal, args...)
+	default:
+		err = runScript(ctx, os.Stdin, terminal, "tiger")
+	}
+	return exitStatus(err)
+}
+
+// run one command per line, stops at exit
+func runScript(ctx *session, r io.Reader, std *stdio, params ...string) error {
+	var err error
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		err = runLine(ctx, line, std, params...)
+		if err == errExit {
+			return nil
+		}
+	}
+	if scanErr := lines.Err(); scanErr != nil {
+		return println("", "", scanErr)
+	}
+	return err
+}
+
+func exitStatus(err error) int {
+	if err == nil || err == errExit {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
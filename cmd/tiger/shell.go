@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// !command runs the rest of the line with $SHELL, so make or go test don't
+// mean leaving tiger. ! by itself starts an interactive shell, exit to come back
+//
+// programs listed in tiger.passthrough (can be specified multiple times) run
+// without the ! instead of being treated as git commands:
+//
+//	git config --global --add tiger.passthrough make
+func (ctx *session) shell(std *stdio, line string) error {
+	sh := os.Getenv("SHELL")
+	if sh == "" {
+		sh = "sh"
+	}
+	args := []string{}
+	if strings.TrimSpace(line) != "" {
+		args = append(args, "-c", line)
+	}
+	return newCmd(sh, args...).AttachIO(std)
+}
+
+func isPassthrough(name string) bool {
+	return contains(strings.Fields(strings.Join(cachedConfig()[configKey("tiger.passthrough")], " ")), name)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	register(&builtin{
+		name:  "summary",
+		usage: "summary",
+		about: "github-style summary of the repository",
+		run: func(ctx *session, args []string, io *stdio) error {
+			return summary(io)
+		},
+	})
+}
+
+func summary(io *stdio) error {
+	lastCommit, _, err := git("log", "-1", "--pretty=%h %an: %s %cr").Output()
+	if err != nil {
+		return nil
+	}
+	commits, _, err := git("rev-list", "HEAD").Output()
+	if err != nil {
+		return err
+	}
+	branches, _, err := git("branch").Output()
+	if err != nil {
+		return err
+	}
+	// NOTE: shortlog reads from stdin when it isn't a terminal
+	authors, _, err := git("shortlog", "-s", "HEAD").Output()
+	if err != nil {
+		return err
+	}
+
+	tw := newTabwriter(io.out)
+	fmt.Fprint(tw, lastCommit)
+	fmt.Fprintf(tw,
+		"commits: %d branches: %d contributors: %d\n",
+		strings.Count(commits, "\n"),
+		strings.Count(branches, "\n"),
+		strings.Count(authors, "\n"),
+	)
+	hasL, _, err := newCmd("which", "l").Output()
+	if hasL != "" && err == nil {
+		l := []struct {
+			Language string
+			Percent  float64
+		}{}
+		data, _, err := newCmd("l", "-json", "-limit", "3").Output()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(data), &l); err != nil {
+			return err
+		}
+		for _, l := range l {
+			fmt.Fprintf(tw, "%s: %.2f%% ", l.Language, l.Percent)
+		}
+	}
+	tw.Flush()
+	return nil
+}
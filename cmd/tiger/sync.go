@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func init() {
+	register(&builtin{
+		name:  "sync",
+		usage: "sync",
+		about: "fetch everything, track every remote branch and fast-forward",
+		run: func(ctx *session, args []string, io *stdio) error {
+			return syncRemotes(io)
+		},
+	})
+}
+
+// syncRemotes brings every local branch up-to-date with the remotes:
+//   - fetch --all and --tags
+//   - prune remote branches that are gone (asks first)
+//   - create a tracking branch for every remote branch we don't have yet
+//   - fast-forward local branches that are strictly behind their upstream
+//   - report branches that have diverged
+func syncRemotes(io *stdio) error {
+	if err := git("fetch", "--all").AttachIO(io); err != nil {
+		return err
+	}
+	if err := git("fetch", "--all", "--tags").AttachIO(io); err != nil {
+		return err
+	}
+
+	stdout, _, err := git("remote").Output()
+	if err != nil {
+		return err
+	}
+	for _, remote := range strings.Fields(stdout) {
+		syncPrune(io, remote)
+	}
+
+	if err := syncTrack(io); err != nil {
+		return err
+	}
+	return syncFastForward(io)
+}
+
+func syncPrune(io *stdio, remote string) {
+	stdout, _, err := git("remote", "prune", "--dry-run", remote).Output()
+	if err != nil {
+		return
+	}
+	gone := []string{}
+	for _, ln := range strings.Split(stdout, "\n") {
+		if i := strings.Index(ln, "[would prune] "); i >= 0 {
+			gone = append(gone, strings.TrimSpace(ln[i+len("[would prune] "):]))
+		}
+	}
+	if len(gone) == 0 {
+		return
+	}
+	fmt.Fprintln(io.out, Yellow+"gone from "+remote+":"+Reset, strings.Join(gone, " "))
+	if confirm("prune them?") {
+		println(git("remote", "prune", remote).Output())
+	}
+}
+
+// what the perl one-liner in the README used to do
+func syncTrack(io *stdio) error {
+	stdout, _, err := git("for-each-ref", "--format=%(refname:short)", "refs/heads").Output()
+	if err != nil {
+		return err
+	}
+	local := strings.Fields(stdout)
+
+	stdout, _, err = git("for-each-ref", "--format=%(refname:short)", "refs/remotes").Output()
+	if err != nil {
+		return err
+	}
+	for _, ref := range strings.Fields(stdout) {
+		i := strings.Index(ref, "/")
+		if i < 0 || strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		branch := ref[i+1:]
+		if contains(local, branch) {
+			continue
+		}
+		_, stderr, err := git("branch", "--track", branch, ref).Output()
+		if err != nil {
+			println("", stderr, err)
+			continue
+		}
+		fmt.Fprintln(io.out, Green+"tracking:"+Reset, branch, "->", ref)
+		local = append(local, branch)
+	}
+	return nil
+}
+
+func syncFastForward(io *stdio) error {
+	stdout, _, err := git("for-each-ref", "--format=%(refname:short) %(upstream:short)", "refs/heads").Output()
+	if err != nil {
+		return err
+	}
+	current, _ := currentBranch()
+
+	for _, ln := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		fields := strings.Fields(ln)
+		if len(fields) != 2 {
+			continue // no upstream
+		}
+		branch, upstream := fields[0], fields[1]
+
+		counts, _, err := git("rev-list", "--left-right", "--count", branch+"..."+upstream).Output()
+		if err != nil {
+			continue // upstream is gone
+		}
+		var ahead, behind int
+		fmt.Sscanf(counts, "%d %d", &ahead, &behind)
+
+		switch {
+		case behind == 0:
+			continue
+		case ahead > 0:
+			fmt.Fprintf(io.out, "%sdiverged:%s %s (%d ahead, %d behind %s)\n", Red, Reset, branch, ahead, behind, upstream)
+			continue
+		}
+
+		if branch == current {
+			err = println(git("merge", "--ff-only", "-q", upstream).Output())
+		} else {
+			// fetch from ourselves: refuses anything that isn't a fast-forward
+			err = println(git("fetch", "-q", ".", upstream+":"+branch).Output())
+		}
+		if err == nil {
+			fmt.Fprintf(io.out, "%supdated:%s %s (%d new commits from %s)\n", Green, Reset, branch, behind, upstream)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// trace on|off [file] prints every command tiger runs, with how long it took
+// and its exit status. TIGER_TRACE works like GIT_TRACE: 1 or true for
+// stderr, an absolute path for a file
+var tracer struct {
+	sync.Mutex
+	w    io.Writer
+	file *os.File
+}
+
+// how many commands have been run, for profile
+var cmdCount int64
+
+func init() {
+	switch t := os.Getenv("TIGER_TRACE"); {
+	case t == "" || t == "0" || strings.EqualFold(t, "false"):
+	case filepath.IsAbs(t):
+		if err := traceOn(t); err != nil {
+			fmt.Fprintln(os.Stderr, "TIGER_TRACE:", err)
+		}
+	default:
+		traceOn("")
+	}
+
+	register(&builtin{
+		name:  "trace",
+		usage: "trace on|off [file]",
+		about: "print every command tiger runs and how long it took",
+		complete: func(args []string) []string {
+			if len(args) > 1 {
+				return completeFiles(lastArg(args), false)
+			}
+			return completeWords(lastArg(args), []string{"on", "off"})
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			if len(args) < 2 {
+				return fmt.Errorf("usage: trace on|off [file]")
+			}
+			switch args[1] {
+			case "on":
+				file := ""
+				if len(args) > 2 {
+					file = args[2]
+				}
+				return traceOn(file)
+			case "off":
+				traceOff()
+				return nil
+			}
+			return fmt.Errorf("usage: trace on|off [file]")
+		},
+	})
+
+	register(&builtin{
+		name:  "profile",
+		usage: "profile [reset]",
+		about: "which parts of the prompt take the longest",
+		complete: func(args []string) []string {
+			return completeWords(lastArg(args), []string{"reset"})
+		},
+		run: func(ctx *session, args []string, io *stdio) error {
+			if len(args) > 1 && args[1] == "reset" {
+				profile.Lock()
+				profile.segments = map[string]*segmentStats{}
+				profile.Unlock()
+				return nil
+			}
+			printProfile(io)
+			return nil
+		},
+	})
+}
+
+// "" means stderr
+func traceOn(file string) error {
+	var f *os.File
+	if file != "" {
+		var err error
+		f, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	traceOff()
+	tracer.Lock()
+	defer tracer.Unlock()
+	tracer.w, tracer.file = os.Stderr, f
+	if f != nil {
+		tracer.w = f
+	}
+	return nil
+}
+
+func traceOff() {
+	tracer.Lock()
+	defer tracer.Unlock()
+	if tracer.file != nil {
+		tracer.file.Close()
+	}
+	tracer.w, tracer.file = nil, nil
+}
+
+func trace(args []string, d time.Duration, err error) {
+	atomic.AddInt64(&cmdCount, 1)
+	tracer.Lock()
+	defer tracer.Unlock()
+	if tracer.w == nil {
+		return
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\|&;<>$") {
+			quoted[i] = fmt.Sprintf("%q", arg)
+		}
+	}
+	status := fmt.Sprint("exit ", exitStatus(err))
+	if err != nil && !isExitErr(err) {
+		status = err.Error()
+	}
+	fmt.Fprintf(tracer.w, "%s %8s  %-7s %s\n",
+		time.Now().Format("15:04:05.000"), d.Round(10*time.Microsecond), status, strings.Join(quoted, " "))
+}
+
+type segmentStats struct {
+	runs  int
+	cmds  int64
+	total time.Duration
+	max   time.Duration
+}
+
+var profile = struct {
+	sync.Mutex
+	segments map[string]*segmentStats
+}{segments: map[string]*segmentStats{}}
+
+// time part of the prompt:
+//
+//	defer profileSegment("status")()
+func profileSegment(name string) func() {
+	start, cmds := time.Now(), atomic.LoadInt64(&cmdCount)
+	return func() {
+		d := time.Since(start)
+		profile.Lock()
+		defer profile.Unlock()
+		s, ok := profile.segments[name]
+		if !ok {
+			s = &segmentStats{}
+			profile.segments[name] = s
+		}
+		s.runs++
+		s.cmds += atomic.LoadInt64(&cmdCount) - cmds
+		s.total += d
+		if d > s.max {
+			s.max = d
+		}
+	}
+}
+
+func printProfile(io *stdio) {
+	profile.Lock()
+	defer profile.Unlock()
+	if len(profile.segments) == 0 {
+		fmt.Fprintln(io.out, "nothing yet")
+		return
+	}
+	names := []string{}
+	for name := range profile.segments {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return profile.segments[names[i]].total > profile.segments[names[j]].total
+	})
+	round := func(d time.Duration) time.Duration { return d.Round(10 * time.Microsecond) }
+	fmt.Fprintf(io.out, "%-14s %6s %10s %10s %10s %9s\n", "", "runs", "avg", "max", "total", "cmds/run")
+	for _, name := range names {
+		s := profile.segments[name]
+		fmt.Fprintf(io.out, "%-14s %6d %10s %10s %10s %9.1f\n", name, s.runs,
+			round(s.total/time.Duration(s.runs)), round(s.max), round(s.total), float64(s.cmds)/float64(s.runs))
+	}
+}
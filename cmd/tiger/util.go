@@ -1,37 +1,19 @@
 package main
 
 import (
-	"io"
 	"os"
 	"strings"
 )
 
-func checkErr(err error) {
-	if err != nil {
-		panic(err)
-	}
-}
-
+// NOTE: a file we're not allowed to look at still exists
 func fileExists(filename string) bool {
-	f, err := os.Open(filename)
-	f.Close()
-	if os.IsNotExist(err) {
-		return false
-	}
-	checkErr(err)
-	return true
+	_, err := os.Stat(filename)
+	return !os.IsNotExist(err)
 }
 
-func fileGetContents(filename string) string {
-	contents := &buf{}
-	f, err := os.Open(filename)
-	checkErr(err)
-	_, err = io.Copy(contents, f)
-	f.Close()
-	if err != io.EOF {
-		checkErr(err)
-	}
-	return contents.String()
+func fileGetContents(filename string) (string, error) {
+	contents, err := os.ReadFile(filename)
+	return string(contents), err
 }
 
 // I'm getting both / and \ as path separators using Git Bash for Windows...
@@ -41,6 +23,14 @@ func normalizePathSeparators(path string) string {
 
 func isDir(filename string) bool {
 	finfo, err := os.Stat(filename)
-	checkErr(err)
-	return finfo.IsDir()
+	return err == nil && finfo.IsDir()
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
 }
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -16,43 +22,89 @@ type event struct {
 }
 
 type watcher struct {
-	w         *fsnotify.Watcher
-	paths     []string
+	w         *fsnotify.Watcher // nil when polling
+	mu        sync.Mutex        // w, mode, paths, root, ignore, pending and rescanning
+	mode      string            // "notify" or "poll"
+	reason    string            // why we're polling
+	forced    bool              // tiger.watch is notify, don't fall back to polling
+	paths     map[string]bool
+	root      string
+	ignore    *ignoreChecker
 	events    chan *event
 	validator func(string) bool
 	callback  func()
-}
+	errors    chan error
 
-func newWatcher(validator func(string) bool, callback func()) (*watcher, error) {
-	w, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, err
-	}
+	// walking new directories happens in walk(), not while events wait
+	pending    map[string]bool
+	wake       chan struct{}
+	rescanning *time.Timer
 
+	generation int64
+	interval   int64 // between polls, time.Duration
+}
+
+// mode is tiger.watch:
+//
+//	auto    inotify (or whatever fsnotify uses), polling if that doesn't work
+//	        or there are too many directories to watch. the default
+//	notify  inotify or nothing
+//	poll    look at every file once in a while, for network filesystems
+//	        where inotify never hears about anything
+func newWatcher(mode string, validator func(string) bool, callback func()) (*watcher, error) {
 	watch := &watcher{
-		w:         w,
-		paths:     []string{},
+		paths:     map[string]bool{},
 		events:    make(chan *event),
 		validator: validator,
 		callback:  callback,
+		errors:    make(chan error, 1),
+		pending:   map[string]bool{},
+		wake:      make(chan struct{}, 1),
 	}
 
-	go watch.relay()
+	switch mode {
+	case "", "auto", "notify":
+		w, err := fsnotify.NewWatcher()
+		if err != nil && mode == "notify" {
+			return nil, err
+		}
+		if err != nil {
+			watch.mode, watch.reason = "poll", err.Error()
+			watch.error(fmt.Errorf("%v, polling for changes instead", err))
+			go watch.poll()
+			break
+		}
+		watch.w, watch.mode, watch.forced = w, "notify", mode == "notify"
+		go watch.relay(w)
+	case "poll":
+		watch.mode, watch.reason = "poll", "tiger.watch is poll"
+		go watch.poll()
+	default:
+		return nil, fmt.Errorf("tiger.watch: unknown mode %q (auto, notify or poll)", mode)
+	}
 	go watch.dispatch()
+	go watch.walk()
 
 	return watch, nil
 }
 
-func (w *watcher) relay() {
+func (w *watcher) relay(fw *fsnotify.Watcher) {
 	for {
 		select {
-		case e := <-w.w.Events:
+		case e, ok := <-fw.Events:
+			if !ok {
+				return // we're polling now
+			}
+			w.track(e)
 			w.events <- &event{
 				filename: normalizePathSeparators(e.Name),
 				t:        time.Now(),
 			}
-		case err := <-w.w.Errors:
-			checkErr(err)
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return
+			}
+			w.error(err)
 		}
 	}
 }
@@ -65,6 +117,7 @@ func (w *watcher) dispatch() {
 			// log.Println("file is not valid,          skipping...")
 			continue
 		}
+		atomic.AddInt64(&w.generation, 1)
 		diff := time.Since(last) - time.Since(e.t)
 		if diff < time.Millisecond*100 {
 			// log.Println("last event was < 100ms ago, skipping...")
@@ -75,23 +128,285 @@ func (w *watcher) dispatch() {
 	}
 }
 
+// report an error without blocking, if there's already one waiting that's enough
+func (w *watcher) error(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+// stop watching whatever was being watched and watch the repository in root
+func (w *watcher) Watch(root string) {
+	if w == nil {
+		return
+	}
+	w.RemoveAll()
+	root = normalizePathSeparators(root)
+	w.mu.Lock()
+	w.root, w.ignore = root, newIgnoreChecker(root)
+	w.mu.Unlock()
+	w.AddWithSubdirs(root)
+}
+
 func (w *watcher) AddWithSubdirs(dir string) {
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() && !strings.Contains(path, ".git") {
-			path = normalizePathSeparators(path)
-			// log.Println("watching", path)
-			w.paths = append(w.paths, path)
-			checkErr(w.w.Add(path))
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	root, polling := w.root, w.w == nil
+	w.mu.Unlock()
+	if polling {
+		return // poll() looks at everything in root anyway
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // can't read it, can't watch it, keep going
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		// not just anything containing ".git", we want .githooks
+		if info.Name() == ".git" {
+			return w.addGitDir(path)
+		}
+		// node_modules and friends, which can easily use up all the watches
+		if path = normalizePathSeparators(path); path != root && w.Ignored(path) {
+			return filepath.SkipDir
 		}
+		return w.add(path)
+	})
+	if err == nil || err == errPolling {
+		return
+	}
+	w.mu.Lock()
+	forced := w.forced
+	w.mu.Unlock()
+	if forced {
+		w.error(err)
+		return
+	}
+	if errors.Is(err, syscall.ENOSPC) {
+		err = fmt.Errorf("%v (fs.inotify.max_user_watches)", err)
+	}
+	w.fallBack(err)
+}
+
+var errPolling = errors.New("polling")
+
+// give up on notifications, poll instead
+func (w *watcher) fallBack(reason error) {
+	w.mu.Lock()
+	fw := w.w
+	if fw == nil {
+		w.mu.Unlock()
+		return
+	}
+	w.w, w.mode, w.reason = nil, "poll", reason.Error()
+	w.paths = map[string]bool{}
+	w.mu.Unlock()
+
+	fw.Close() // all the watches go with it, and relay() stops
+	w.error(fmt.Errorf("%v, polling for changes instead", reason))
+	go w.poll()
+}
+
+// only .git itself (for HEAD, index and packed-refs) and refs/, the rest of
+// it changes all the time and isn't in the prompt. see isGitState()
+func (w *watcher) addGitDir(dir string) error {
+	if err := w.add(dir); err != nil {
 		return err
+	}
+	err := filepath.Walk(filepath.Join(dir, "refs"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		return w.add(path)
 	})
+	if err != nil {
+		return err
+	}
+	return filepath.SkipDir
+}
+
+func (w *watcher) add(path string) error {
+	path = normalizePathSeparators(path)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.w == nil {
+		return errPolling
+	}
+	if w.paths[path] {
+		return nil
+	}
+	// log.Println("watching", path)
+	if err := w.w.Add(path); err != nil {
+		return fmt.Errorf("watching %s: %w", path, err)
+	}
+	w.paths[path] = true
+	return nil
+}
+
+// forget about dir and everything in it
+func (w *watcher) remove(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.w == nil {
+		return
+	}
+	for path := range w.paths {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			// already gone if it was deleted, nothing to worry about
+			w.w.Remove(path)
+			delete(w.paths, path)
+		}
+	}
+}
+
+// keep up with directories coming and going
+func (w *watcher) track(e fsnotify.Event) {
+	name := normalizePathSeparators(e.Name)
+	switch {
+	case path.Base(name) == ".gitignore":
+		w.mu.Lock()
+		ignore := w.ignore
+		w.mu.Unlock()
+		ignore.reset()
+		// something that was ignored might not be anymore
+		w.rescanSoon()
+		return
+	case e.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		w.remove(name)
+	case e.Op&fsnotify.Create != 0:
+		// not .git/objects/ab and such, but .git/refs/heads/feature/ is fine
+		if isDir(name) && (!strings.Contains(name, "/.git/") || isGitState(name)) {
+			w.addLater(name)
+		}
+	}
+	// switching branches can add and remove whole trees, make sure nothing
+	// got missed
+	if strings.HasSuffix(name, "/.git/HEAD") {
+		w.rescanSoon()
+	}
+}
+
+// a new directory could be node_modules or a whole checkout, walk it
+// without holding up the events behind it
+func (w *watcher) addLater(dir string) {
+	w.mu.Lock()
+	w.pending[dir] = true
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *watcher) walk() {
+	for range w.wake {
+		w.mu.Lock()
+		dirs, root := w.pending, w.root
+		w.pending = map[string]bool{}
+		w.mu.Unlock()
+	next:
+		for dir := range dirs {
+			if root == "" || !strings.HasPrefix(dir, root+"/") {
+				continue // cd'd somewhere else in the meantime
+			}
+			// walking the parent takes care of it
+			for p := path.Dir(dir); p != root && p != "/" && p != "."; p = path.Dir(p) {
+				if dirs[p] {
+					continue next
+				}
+			}
+			w.AddWithSubdirs(dir)
+		}
+	}
+}
+
+// a checkout writes HEAD more than once and a rebase does for every commit,
+// rescan once after things calm down
+const rescanDelay = 500 * time.Millisecond
+
+func (w *watcher) rescanSoon() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.rescanning != nil {
+		w.rescanning.Stop()
+	}
+	root := w.root
+	w.rescanning = time.AfterFunc(rescanDelay, func() {
+		w.mu.Lock()
+		same := w.root == root
+		w.mu.Unlock()
+		if same {
+			w.rescan(root)
+		}
+	})
+}
+
+// watch what's there and isn't watched yet, forget what's not there anymore
+func (w *watcher) rescan(dir string) {
+	if dir == "" {
+		return
+	}
+	w.mu.Lock()
+	gone := []string{}
+	for path := range w.paths {
+		if !isDir(path) {
+			gone = append(gone, path)
+		}
+	}
+	w.mu.Unlock()
+	for _, path := range gone {
+		w.remove(path)
+	}
+	w.AddWithSubdirs(dir)
+}
+
+// filename is somewhere in .git/, is it something the prompt shows?
+func isGitState(filename string) bool {
+	i := strings.LastIndex(filename, "/.git/")
+	if i < 0 {
+		return false
+	}
+	name := filename[i+len("/.git/"):]
+	if strings.HasSuffix(name, ".lock") {
+		return false
+	}
+	return name == "HEAD" || name == "index" || name == "packed-refs" || strings.HasPrefix(name, "refs/")
 }
 
 func (w *watcher) RemoveAll() {
-	for _, path := range w.paths {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path := range w.paths {
 		if err := w.w.Remove(path); err != nil {
 			log.Println(err)
 		}
 	}
-	w.paths = []string{}
+	w.paths = map[string]bool{}
+	w.pending = map[string]bool{}
+	if w.rescanning != nil {
+		w.rescanning.Stop()
+	}
+	w.ignore.close()
+	w.root, w.ignore = "", nil
+}
+
+// whether git ignores filename, which is in the repository being watched
+func (w *watcher) Ignored(filename string) bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	ignore := w.ignore
+	w.mu.Unlock()
+	return ignore.ignored(filename)
 }
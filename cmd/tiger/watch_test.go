@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestReadCheckIgnore(t *testing.T) {
+	for _, test := range []struct {
+		output  string
+		ignored bool
+	}{
+		{".gitignore\x003\x00*.log\x00debug.log\x00", true},
+		{".gitignore\x004\x00!keep.log\x00keep.log\x00", false},
+		{"\x00\x00\x00main.go\x00", false},
+		{"/home/x/.config/git/ignore\x001\x00node_modules/\x00node_modules\x00", true},
+	} {
+		ignored, err := readCheckIgnore(bufio.NewReader(strings.NewReader(test.output)))
+		if err != nil || ignored != test.ignored {
+			t.Fatalf("%q: expected %v, got %v %v", test.output, test.ignored, ignored, err)
+		}
+	}
+
+	// git went away halfway through
+	if _, err := readCheckIgnore(bufio.NewReader(strings.NewReader(".gitignore\x003\x00"))); err == nil {
+		t.Fatal("expected an error for a cut off answer")
+	}
+}
+
+func TestIgnoreCheckerClosed(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip(err)
+	}
+	c := newIgnoreChecker(t.TempDir())
+	c.close()
+	if c.ignored("x.log") || c.cmd != nil {
+		t.Fatal("a closed checker started git check-ignore again")
+	}
+}
+
+func TestIsGitState(t *testing.T) {
+	for filename, expected := range map[string]bool{
+		"/src/x/.git/HEAD":                  true,
+		"/src/x/.git/index":                 true,
+		"/src/x/.git/index.lock":            false,
+		"/src/x/.git/packed-refs":           true,
+		"/src/x/.git/refs/heads/main":       true,
+		"/src/x/.git/refs/heads/main.lock":  false,
+		"/src/x/.git/refs/tags/v1":          true,
+		"/src/x/.git/objects/ab/cdef":       false,
+		"/src/x/.git/logs/HEAD":             false,
+		"/src/x/.git/COMMIT_EDITMSG":        false,
+		"/src/x/HEAD":                       false,
+		"/src/x/.github/HEAD":               false,
+		"/src/x/vendor/y/.git/refs/heads/a": true,
+	} {
+		if isGitState(filename) != expected {
+			t.Fatalf("isGitState(%q) should be %v", filename, expected)
+		}
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	before := map[string]fileStamp{
+		"same":     {1, 10},
+		"touched":  {1, 10},
+		"grew":     {1, 10},
+		"deleted":  {1, 10},
+		"replaced": {1, 10},
+	}
+	after := map[string]fileStamp{
+		"same":     {1, 10},
+		"touched":  {2, 10},
+		"grew":     {1, 11},
+		"replaced": {1, 10},
+		"created":  {3, 0},
+	}
+	for _, test := range []struct {
+		before, after map[string]fileStamp
+		expected      []string
+	}{
+		{before, after, []string{"created", "deleted", "grew", "touched"}},
+		{before, before, []string{}},
+		{nil, map[string]fileStamp{"a": {}}, []string{"a"}},
+		{map[string]fileStamp{"a": {}}, nil, []string{"a"}},
+	} {
+		actual := changedFiles(test.before, test.after)
+		sort.Strings(actual)
+		if !reflect.DeepEqual(test.expected, actual) {
+			t.Fatalf("expected: %#v\nactual:   %#v", test.expected, actual)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, kv := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(kv, "tiger@example.com")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"gone.go", "removed.go", "changed.go"} {
+		if err := os.WriteFile(dir+"/"+name, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitIn(t, dir, "init", "-q")
+	gitIn(t, dir, "add", ".")
+	gitIn(t, dir, "commit", "-q", "-m", "x")
+	gitIn(t, dir, "rm", "-q", "removed.go")
+	if err := os.Remove(dir + "/gone.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/changed.go", []byte("package y\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/new.go", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	_, files := status(gitIn(t, dir, "diff", "--numstat"))
+	expected := map[string]string{
+		"gone.go":            Red + "- " + Reset + "gone.go " + Green + "+0" + Reset + "/" + Red + "-1" + Reset,
+		"staged: removed.go": "staged: removed.go " + Green + "+0" + Reset + "/" + Red + "-1" + Reset,
+		"changed.go":         Yellow + "~ " + Reset + "changed.go " + Green + "+1" + Reset + "/" + Red + "-1" + Reset,
+		"new.go":             Green + "+ " + Reset + "new.go",
+	}
+	if !reflect.DeepEqual(expected, files) {
+		t.Fatalf("expected: %#v\nactual:   %#v", expected, files)
+	}
+}
+
+func TestStatusDelta(t *testing.T) {
+	modified := Yellow + "~ " + Reset + "a.go +1/-0"
+	for _, test := range []struct {
+		before, after map[string]string
+		expected      []string
+	}{
+		// the first update
+		{nil, map[string]string{"a.go": modified}, []string{modified}},
+		{map[string]string{"a.go": modified}, map[string]string{"a.go": modified}, []string{}},
+		{
+			map[string]string{"a.go": modified},
+			map[string]string{"a.go": Yellow + "~ " + Reset + "a.go +2/-0"},
+			[]string{Yellow + "~ " + Reset + "a.go +2/-0"},
+		},
+		{map[string]string{"a.go": modified}, nil, []string{Grey + "= " + Reset + "a.go"}},
+		{
+			map[string]string{"staged: b.go": "staged: b.go +1/-0"},
+			map[string]string{"b.go": Yellow + "~ " + Reset + "b.go +1/-0"},
+			[]string{Yellow + "~ " + Reset + "b.go +1/-0", Grey + "unstaged: " + Reset + "b.go"},
+		},
+		// committed
+		{map[string]string{"staged: b.go": "staged: b.go +1/-0"}, nil, []string{}},
+		{
+			map[string]string{"c.go": Green + "+ " + Reset + "untracked: c.go"},
+			map[string]string{"staged: c.go": "staged: c.go +1/-0"},
+			[]string{"staged: c.go +1/-0"},
+		},
+	} {
+		actual := statusDelta(test.before, test.after)
+		if !reflect.DeepEqual(test.expected, actual) {
+			t.Fatalf("expected: %#v\nactual:   %#v", test.expected, actual)
+		}
+	}
+}